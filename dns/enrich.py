@@ -0,0 +1,139 @@
+"""Bulk PTR enrichment for arbitrary JSON or CSV data.
+
+Resolves every IP address found at the given field paths and adds the
+hostname alongside the original, as <field>_hostname. Lookups run
+concurrently through resolve_ptr(), sharing its cache.
+
+Field paths are dotted keys, with * matching every item of a list or dict.
+For CSV they're column names.
+
+    python enrich.py flows.csv -f src_ip -f dst_ip > flows_enriched.csv
+    python enrich.py matches.json -f "results.*.source" -f "results.*.destination"
+    cat logs.json | python enrich.py - --format json -f "*.client.ip"
+"""
+
+import argparse
+import csv
+import ipaddress
+import json
+import sys
+from collections.abc import Iterator
+from concurrent.futures import ThreadPoolExecutor
+
+from resolve_ptr import resolve_ptr
+
+
+def is_ip(value) -> bool:
+    if not isinstance(value, str):
+        return False
+    try:
+        ipaddress.ip_address(value.strip())
+    except ValueError:
+        return False
+    return True
+
+
+def find(data, path: list[str]) -> Iterator[tuple[dict, str]]:
+    """Yields (container, key) for every dict key matching a field path."""
+    if not path:
+        return
+    key, rest = path[0], path[1:]
+    if key == "*":
+        if isinstance(data, dict):
+            data = data.values()
+        elif not isinstance(data, list):
+            return
+        for child in data:
+            yield from find(child, rest)
+        return
+    if not isinstance(data, dict) or key not in data:
+        return
+    if rest:
+        yield from find(data[key], rest)
+    else:
+        yield data, key
+
+
+def addresses(value) -> list[str]:
+    """IP addresses in a field value: a single address or a list of them."""
+    values = value if isinstance(value, list) else [value]
+    return [v.strip() for v in values if is_ip(v)]
+
+
+def resolve_all(ips: set[str], workers: int = 32) -> dict[str, str]:
+    with ThreadPoolExecutor(max_workers=workers) as pool:
+        hostnames = pool.map(lambda ip: resolve_ptr(ip_address=ip), ips)
+    return dict(zip(ips, hostnames))
+
+
+def enrich_json(data, paths: list[str], workers: int = 32):
+    """Adds <field>_hostname next to every IP address field matching paths.
+
+    List values get a list of hostnames. Fields without IP addresses are left alone.
+    """
+    matches = [match for path in paths for match in find(data, path.split("."))]
+    hostnames = resolve_all(
+        {ip for container, key in matches for ip in addresses(container[key])}, workers
+    )
+    for container, key in matches:
+        value = container[key]
+        if isinstance(value, list):
+            if found := addresses(value):
+                container[f"{key}_hostname"] = [hostnames[ip] for ip in found]
+        elif is_ip(value):
+            container[f"{key}_hostname"] = hostnames[value.strip()]
+    return data
+
+
+def enrich_csv(rows: list[dict], columns: list[str], workers: int = 32) -> tuple[list[str], list]:
+    """Adds a <column>_hostname column after each given column.
+
+    Returns:
+        The new header and rows.
+    """
+    hostnames = resolve_all(
+        {row[column].strip() for row in rows for column in columns if is_ip(row.get(column))},
+        workers,
+    )
+    for row in rows:
+        for column in columns:
+            value = (row.get(column) or "").strip()
+            row[f"{column}_hostname"] = hostnames.get(value, "")
+    return columns, rows
+
+
+def main():
+    parser = argparse.ArgumentParser(description=__doc__.splitlines()[0])
+    parser.add_argument("input", help="JSON or CSV file, or - for stdin")
+    parser.add_argument("-f", "--field", action="append", required=True, dest="fields")
+    parser.add_argument("--format", choices=["json", "csv"], help="Defaults to the file extension")
+    parser.add_argument("-o", "--output", help="Output file (default stdout)")
+    parser.add_argument("--workers", type=int, default=32)
+    args = parser.parse_args()
+
+    fmt = args.format or ("csv" if args.input.endswith(".csv") else "json")
+    source = sys.stdin if args.input == "-" else open(args.input, newline="")
+    output = open(args.output, "w", newline="") if args.output else sys.stdout
+    with source, output:
+        if fmt == "json":
+            data = enrich_json(json.load(source), args.fields, args.workers)
+            json.dump(data, output, indent=2)
+            output.write("\n")
+            return
+        reader = csv.DictReader(source)
+        missing = set(args.fields) - set(reader.fieldnames or [])
+        if missing:
+            parser.error(f"Columns not found: {', '.join(sorted(missing))}")
+        columns, rows = enrich_csv(list(reader), args.fields, args.workers)
+        header = []
+        for name in reader.fieldnames:
+            header.append(name)
+            if name in columns:
+                header.append(f"{name}_hostname")
+        writer = csv.DictWriter(output, fieldnames=header)
+        writer.writeheader()
+        writer.writerows(rows)
+
+
+if __name__ == "__main__":
+    main()
@@ -1,10 +1,25 @@
-from config import traceroute_results
 from rich import print
 
 
 import re
 import socket
 import ipaddress
+import threading
+import time
+
+import config
+from opentelemetry import trace
+
+tracer = trace.get_tracer(__name__)
+
+# Resolved names are shared by everything that resolves through resolve_ptr(),
+# for PTR_CACHE_TTL seconds. At most PTR_CACHE_SIZE are kept, dropping the
+# oldest first. Failed lookups aren't cached, so they're retried next time.
+CACHE_TTL = getattr(config, 'PTR_CACHE_TTL', 60 * 60)
+CACHE_SIZE = getattr(config, 'PTR_CACHE_SIZE', 10_000)
+_cache: dict[str, tuple[float, str]] = {}
+_cache_lock = threading.Lock()
+
 
 def resolve_ptr(ip_address: ipaddress.IPv4Address | str) -> str:
     """Resolve an IP address to a PTR record, if possible.
@@ -18,10 +33,24 @@ def resolve_ptr(ip_address: ipaddress.IPv4Address | str) -> str:
     Raises:
         N/A
     """
-    try:
-        return socket.gethostbyaddr(str(ip_address))[0]
-    except Exception:
-        return ip_address
+    key = str(ip_address)
+    with _cache_lock:
+        cached = _cache.get(key)
+    if cached and cached[0] > time.monotonic():
+        return cached[1]
+
+    with tracer.start_as_current_span("dns.ptr", attributes={"ip_address": key}):
+        try:
+            hostname = socket.gethostbyaddr(key)[0]
+        except Exception:
+            return ip_address
+
+    with _cache_lock:
+        _cache.pop(key, None)
+        _cache[key] = (time.monotonic() + CACHE_TTL, hostname)
+        while len(_cache) > CACHE_SIZE:
+            del _cache[next(iter(_cache))]
+    return hostname
 
 def resolve_traceroute_ptrs(traceroute_results: dict) -> dict:
     """Resolves PTR (reverse DNS) records for traceroute results.
@@ -37,8 +66,7 @@ def resolve_traceroute_ptrs(traceroute_results: dict) -> dict:
     IP_ADDRESS_PATTERN = r'^\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}$'
     
     # As each hop has multiple probes, the same IP address will be
-    # resolved more than once. Caching these values may save a few ms.
-    cache = {}
+    # resolved more than once. resolve_ptr() caches these values.
 
     # If the results dict has an error key, there's nothing to do
     if traceroute_results.get('error'):
@@ -47,11 +75,10 @@ def resolve_traceroute_ptrs(traceroute_results: dict) -> dict:
     for probes in traceroute_results['success'].values():
         for result in probes['probes'].values():
             if re.match(IP_ADDRESS_PATTERN, result['host_name']):
-                hostname = cache.get(result['host_name']) or resolve_ptr(ip_address=result['host_name'])
-                cache.update({result['host_name']: hostname})
-                result['host_name'] = hostname
+                result['host_name'] = resolve_ptr(ip_address=result['host_name'])
         
     return traceroute_results
 
 if __name__ == '__main__':
+    from config import traceroute_results
     print(resolve_traceroute_ptrs(traceroute_results=traceroute_results))
\ No newline at end of file
@@ -0,0 +1,170 @@
+"""Identify traceroute hops over SNMP where PTR records are missing.
+
+Queries each hop IP for sysName, sysDescr and, through ipAddrTable, the
+ifDescr of the interface that owns the address. Results are added to each
+probe of the resolve_traceroute_ptrs() output under an "snmp" key.
+
+Credentials come from Vault (KV v2) when config.VAULT_URL is set, otherwise
+from config.SNMP_CREDENTIALS. Either way they look like:
+
+    {"version": "2c", "community": "public"}
+    {"version": "3", "username": "...", "auth_key": "...", "priv_key": "...",
+     "auth_protocol": "sha", "priv_protocol": "aes"}
+
+Leave out priv_key for authNoPriv, and auth_key as well for noAuthNoPriv.
+
+To test against snmpsim:
+
+    snmpsim-command-responder --data-dir=snmpsim --agent-udpv4-endpoint=127.0.0.1:1161
+
+and set SNMP_AGENT = ("127.0.0.1", 1161) to send every query there.
+"""
+
+import config
+import httpx
+from pysnmp.hlapi import (
+    CommunityData,
+    ContextData,
+    ObjectIdentity,
+    ObjectType,
+    SnmpEngine,
+    UdpTransportTarget,
+    UsmUserData,
+    getCmd,
+    usmAesCfb128Protocol,
+    usmDESPrivProtocol,
+    usmHMACMD5AuthProtocol,
+    usmHMACSHAAuthProtocol,
+)
+from resolve_ptr import resolve_traceroute_ptrs
+from rich import print
+
+SYS_DESCR = "1.3.6.1.2.1.1.1.0"
+SYS_NAME = "1.3.6.1.2.1.1.5.0"
+IP_AD_ENT_IF_INDEX = "1.3.6.1.2.1.4.20.1.2"
+IF_DESCR = "1.3.6.1.2.1.2.2.1.2"
+
+AUTH_PROTOCOLS = {"md5": usmHMACMD5AuthProtocol, "sha": usmHMACSHAAuthProtocol}
+PRIV_PROTOCOLS = {"des": usmDESPrivProtocol, "aes": usmAesCfb128Protocol}
+
+
+def get_snmp_credentials() -> dict:
+    """Reads SNMP credentials from Vault, falling back to config.SNMP_CREDENTIALS."""
+    if not getattr(config, "VAULT_URL", None):
+        return config.SNMP_CREDENTIALS
+    resp = httpx.get(
+        f"{config.VAULT_URL}/v1/{config.VAULT_SNMP_PATH}",
+        headers={"X-Vault-Token": config.VAULT_TOKEN},
+    )
+    resp.raise_for_status()
+    return resp.json()["data"]["data"]
+
+
+def auth_data(credentials: dict):
+    if credentials["version"] == "2c":
+        return CommunityData(credentials["community"], mpModel=1)
+    # Only pass a protocol with its key, so authNoPriv and noAuthNoPriv users work
+    security = {}
+    if credentials.get("auth_key"):
+        security["authKey"] = credentials["auth_key"]
+        security["authProtocol"] = AUTH_PROTOCOLS[credentials.get("auth_protocol", "sha")]
+    if credentials.get("priv_key"):
+        security["privKey"] = credentials["priv_key"]
+        security["privProtocol"] = PRIV_PROTOCOLS[credentials.get("priv_protocol", "aes")]
+    return UsmUserData(credentials["username"], **security)
+
+
+def snmp_get(
+    agent: tuple[str, int], credentials: dict, *oids: str, timeout: int = 2
+) -> dict[str, str]:
+    """Gets OIDs from an agent.
+
+    Returns:
+        Dict of OID to value, leaving out OIDs the agent doesn't have.
+        Empty if the agent doesn't respond.
+    """
+    error_indication, error_status, _, var_binds = next(
+        getCmd(
+            SnmpEngine(),
+            auth_data(credentials),
+            UdpTransportTarget(agent, timeout=timeout, retries=0),
+            ContextData(),
+            *(ObjectType(ObjectIdentity(oid)) for oid in oids),
+        )
+    )
+    if error_indication or error_status:
+        return {}
+    return {
+        str(name): value.prettyPrint()
+        for name, value in var_binds
+        if value.__class__.__name__ not in ("NoSuchObject", "NoSuchInstance", "EndOfMibView")
+    }
+
+
+def identify_hop(
+    ip_address: str, credentials: dict, agent: tuple[str, int] | None = None
+) -> dict | None:
+    """Looks up sysName, sysDescr and the ifDescr of the interface owning an IP.
+
+    Args:
+        ip_address: Hop IP address.
+        credentials: SNMP credentials.
+        agent: Query this (host, port) instead of the hop itself, e.g. snmpsim.
+
+    Returns:
+        Dict with sys_name, sys_descr and if_descr, or None if the hop
+        doesn't answer.
+    """
+    agent = agent or (ip_address, 161)
+    if_index_oid = f"{IP_AD_ENT_IF_INDEX}.{ip_address}"
+    values = snmp_get(agent, credentials, SYS_NAME, SYS_DESCR, if_index_oid)
+    if not values:
+        return None
+    if_descr = None
+    if if_index := values.get(if_index_oid):
+        if_descr_oid = f"{IF_DESCR}.{if_index}"
+        if_descr = snmp_get(agent, credentials, if_descr_oid).get(if_descr_oid)
+    return {
+        "sys_name": values.get(SYS_NAME),
+        "sys_descr": values.get(SYS_DESCR),
+        "if_descr": if_descr,
+    }
+
+
+def enrich_traceroute_snmp(
+    traceroute_results: dict,
+    credentials: dict | None = None,
+    only_unresolved: bool = True,
+    agent: tuple[str, int] | None = None,
+) -> dict:
+    """Annotates resolve_traceroute_ptrs() output with SNMP identification.
+
+    Args:
+        traceroute_results: Dict of traceroute results, after PTR resolution.
+        credentials: SNMP credentials. Defaults to get_snmp_credentials().
+        only_unresolved: Only query hops whose PTR lookup failed.
+        agent: Send every query to this (host, port), e.g. snmpsim.
+
+    Returns:
+        Same dict, with an "snmp" key on each queried probe.
+    """
+    if traceroute_results.get("error"):
+        return traceroute_results
+    credentials = credentials or get_snmp_credentials()
+    cache = {}
+    for probes in traceroute_results["success"].values():
+        for result in probes["probes"].values():
+            ip_address = result.get("ip_address")
+            if not ip_address or ip_address == "*":
+                continue
+            if only_unresolved and result["host_name"] != ip_address:
+                continue
+            if ip_address not in cache:
+                cache[ip_address] = identify_hop(ip_address, credentials, agent)
+            result["snmp"] = cache[ip_address]
+    return traceroute_results
+
+
+if __name__ == "__main__":
+    results = resolve_traceroute_ptrs(traceroute_results=config.traceroute_results)
+    print(enrich_traceroute_snmp(results, agent=getattr(config, "SNMP_AGENT", None)))
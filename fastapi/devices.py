@@ -0,0 +1,44 @@
+"""Device operations behind the API.
+
+These wrap the functions in the sibling script directories, which create
+their own spans (NAPALM traceroutes, PTR lookups); only the connection
+handling here is traced separately.
+"""
+
+import sys
+from pathlib import Path
+
+import config
+from opentelemetry import trace
+
+from napalm import get_network_driver
+
+REPO = Path(__file__).resolve().parent.parent
+sys.path += [str(REPO / "dns"), str(REPO / "napalm")]
+import traceroute as napalm_traceroute  # noqa: E402
+from resolve_ptr import resolve_traceroute_ptrs  # noqa: E402
+
+tracer = trace.get_tracer(__name__)
+
+
+def traceroute(
+    device: dict,
+    destination: str,
+    source: str | None = None,
+    dscp: int | None = None,
+    payload_size: int | None = None,
+) -> dict:
+    """Runs a NAPALM traceroute from an inventory device and resolves PTRs."""
+    driver = get_network_driver(device["platform"])
+    attributes = {"host": device["host"], "platform": device["platform"]}
+    with tracer.start_as_current_span("napalm.open", attributes=attributes):
+        conn = driver(device["host"], config.USERNAME, config.PASSWORD)
+        conn.open()
+    try:
+        results = napalm_traceroute.traceroute(
+            conn, destination, source or "", dscp=dscp, payload_size=payload_size
+        )
+    finally:
+        with tracer.start_as_current_span("napalm.close", attributes=attributes):
+            conn.close()
+    return resolve_traceroute_ptrs(results)
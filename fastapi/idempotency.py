@@ -0,0 +1,129 @@
+"""Idempotency-Key support for POST endpoints that run device operations.
+
+Applied to every POST request (e.g. POST /traceroute/{hostname}) as
+middleware, so endpoints need nothing of their own. A repeated request with
+the same key (per tenant) within the retention window gets the original
+response back instead of running again. Reusing a key for a different
+request is rejected with 422, and a repeat that arrives while the original
+is still running gets 409. Server errors aren't kept, so a retry after one
+runs again.
+
+Responses are kept in memory, so requests are only deduplicated within one
+process: run the API with a single uvicorn worker, and expect a restart to
+forget every key. Keys expire after IDEMPOTENCY_RETENTION seconds, and at
+most IDEMPOTENCY_MAX_KEYS are kept, dropping the oldest first.
+"""
+
+import hashlib
+import time
+from dataclasses import dataclass
+
+import config
+from tenancy import tenant_for_api_key
+
+from fastapi.datastructures import Headers
+from fastapi.responses import JSONResponse
+
+RETENTION = getattr(config, "IDEMPOTENCY_RETENTION", 24 * 60 * 60)
+MAX_KEYS = getattr(config, "IDEMPOTENCY_MAX_KEYS", 10_000)
+
+
+@dataclass
+class StoredResponse:
+    expires: float
+    fingerprint: str
+    # ASGI response start message and body; None while the request runs
+    start: dict | None = None
+    body: bytes = b""
+
+
+# (tenant, key) -> response, oldest first
+_responses: dict[tuple[str, str], StoredResponse] = {}
+
+
+def _expire(now: float):
+    for stored_key, stored in list(_responses.items()):
+        if stored.expires < now:
+            del _responses[stored_key]
+    while len(_responses) >= MAX_KEYS:
+        del _responses[next(iter(_responses))]
+
+
+async def _read_body(receive) -> bytes:
+    body, more = b"", True
+    while more:
+        message = await receive()
+        body += message.get("body", b"")
+        more = message.get("more_body", False)
+    return body
+
+
+def _replay_body(body: bytes, receive):
+    """An ASGI receive that hands the already read body to the endpoint."""
+    sent = False
+
+    async def replay():
+        nonlocal sent
+        if sent:
+            return await receive()
+        sent = True
+        return {"type": "http.request", "body": body, "more_body": False}
+
+    return replay
+
+
+class IdempotencyMiddleware:
+    def __init__(self, app):
+        self.app = app
+
+    async def __call__(self, scope, receive, send):
+        if scope["type"] != "http" or scope["method"] != "POST":
+            return await self.app(scope, receive, send)
+        headers = Headers(scope=scope)
+        key = headers.get("idempotency-key")
+        tenant = tenant_for_api_key(headers.get("x-api-key"))
+        # Without a key there's nothing to deduplicate; without a tenant the endpoint rejects it
+        if not key or not tenant:
+            return await self.app(scope, receive, send)
+
+        body = await _read_body(receive)
+        fingerprint = hashlib.sha256(
+            scope["path"].encode() + b"?" + scope["query_string"] + b"\n" + body
+        ).hexdigest()
+        stored_key = (tenant.name, key)
+        now = time.monotonic()
+        _expire(now)
+        if stored := _responses.get(stored_key):
+            if stored.fingerprint != fingerprint:
+                response = JSONResponse(
+                    {"detail": "Idempotency-Key was already used for another request"}, 422
+                )
+            elif stored.start is None:
+                response = JSONResponse(
+                    {"detail": "A request with this Idempotency-Key is still running"}, 409
+                )
+            else:
+                headers = [*stored.start["headers"], (b"idempotent-replayed", b"true")]
+                await send({**stored.start, "headers": headers})
+                await send({"type": "http.response.body", "body": stored.body})
+                return
+            return await response(scope, receive, send)
+
+        stored = _responses[stored_key] = StoredResponse(now + RETENTION, fingerprint)
+        start, chunks = None, []
+
+        async def capture(message):
+            nonlocal start
+            if message["type"] == "http.response.start":
+                start = message
+            elif message["type"] == "http.response.body":
+                chunks.append(message.get("body", b""))
+            await send(message)
+
+        try:
+            await self.app(scope, _replay_body(body, receive), capture)
+        finally:
+            if start is None or start["status"] >= 500:
+                _responses.pop(stored_key, None)
+            else:
+                stored.start, stored.body = start, b"".join(chunks)
@@ -2,14 +2,22 @@
 https://fastapi.tiangolo.com/#example
 """
 
+import json
 from typing import Annotated, Literal
 
-from models import Item
+import config
+import devices
+from idempotency import IdempotencyMiddleware
+from models import Item, TracerouteRequest
+from tenancy import TenantDep, authorize_device, tenant_devices
+from tracing import setup_tracing
 
-from fastapi import FastAPI, Query
+from fastapi import FastAPI, HTTPException, Query
 import uvicorn
 
 app = FastAPI()
+app.add_middleware(IdempotencyMiddleware)
+setup_tracing(app)
 
 
 @app.get("/")
@@ -35,12 +43,49 @@ async def save_item(item: Item):
     return {"item_name": item.name, "item_price": item.price, "item_id": item.id}
 
 
+@app.get("/devices")
+async def read_devices(tenant: TenantDep):
+    return tenant_devices(tenant)
+
+
 @app.get("/generate-config/{hostname}")
 async def generate_config(
-    hostname: str, format: Literal["json", "yaml"] = "json", force_fresh: bool = False
+    hostname: str,
+    tenant: TenantDep,
+    format: Literal["json", "yaml"] = "json",
+    force_fresh: bool = False,
 ):
+    authorize_device(hostname, tenant)
     return {"hostname": hostname, "format": format}
 
 
+@app.get("/neighbors")
+async def read_neighbors(tenant: TenantDep):
+    """Latest routing neighbor report from napalm/neighbors.py, for the tenant's devices."""
+    try:
+        with open(config.NEIGHBOR_REPORT) as f:
+            report = json.load(f)
+    except FileNotFoundError:
+        raise HTTPException(
+            status_code=503, detail="No neighbor report yet; run napalm/neighbors.py first"
+        )
+    allowed = tenant_devices(tenant)
+    return {
+        "generated": report["generated"],
+        "problems": [p for p in report["problems"] if p["device"] in allowed],
+        "errors": {device: e for device, e in report["errors"].items() if device in allowed},
+    }
+
+
+@app.post("/traceroute/{hostname}")
+def run_traceroute(hostname: str, request: TracerouteRequest, tenant: TenantDep):
+    """Traces from a device, with PTR names. Send an Idempotency-Key to make retries safe."""
+    device = authorize_device(hostname, tenant)
+    try:
+        return devices.traceroute(device, **request.model_dump())
+    except NotImplementedError as e:
+        raise HTTPException(status_code=400, detail=str(e))
+
+
 if __name__ == "__main__":
     uvicorn.run(app=app, port=8000)
@@ -1,4 +1,4 @@
-from pydantic import BaseModel
+from pydantic import BaseModel, Field
 
 
 class Item(BaseModel):
@@ -6,3 +6,16 @@ class Item(BaseModel):
     name: str
     price: float
     is_offer: bool | None = None
+
+
+class Tenant(BaseModel):
+    name: str
+    api_keys: list[str]
+    inventory_groups: list[str] = []
+
+
+class TracerouteRequest(BaseModel):
+    destination: str
+    source: str | None = None
+    dscp: int | None = Field(None, ge=0, le=63)
+    payload_size: int | None = Field(None, gt=0)
@@ -0,0 +1,64 @@
+"""Team-scoped access to devices.
+
+Tenants are defined in config.py over inventory groups, and identify
+themselves with an X-API-Key header:
+
+    TENANTS = {
+        "netops": {
+            "api_keys": ["..."],
+            "inventory_groups": ["core", "wan"],
+        },
+    }
+
+Endpoints take a TenantDep parameter and check every device they act on with
+authorize_device(), or list only the tenant's devices with tenant_devices().
+"""
+
+from typing import Annotated
+
+import config
+import yaml
+from models import Tenant
+
+from fastapi import Depends, Header, HTTPException
+
+TENANTS = [Tenant(name=name, **tenant) for name, tenant in config.TENANTS.items()]
+
+
+def load_inventory() -> dict:
+    """Devices from the inventory file, keyed by hostname."""
+    with open(config.INVENTORY) as f:
+        return (yaml.safe_load(f) or {}).get("devices") or {}
+
+
+def tenant_for_api_key(api_key: str | None) -> Tenant | None:
+    for tenant in TENANTS:
+        if api_key in tenant.api_keys:
+            return tenant
+    return None
+
+
+async def get_tenant(x_api_key: Annotated[str, Header()]) -> Tenant:
+    if tenant := tenant_for_api_key(x_api_key):
+        return tenant
+    raise HTTPException(status_code=401, detail="Invalid API key")
+
+
+TenantDep = Annotated[Tenant, Depends(get_tenant)]
+
+
+def tenant_devices(tenant: Tenant) -> dict:
+    """Inventory devices that belong to any of the tenant's inventory groups."""
+    return {
+        hostname: device
+        for hostname, device in load_inventory().items()
+        if set(device.get("groups") or []) & set(tenant.inventory_groups)
+    }
+
+
+def authorize_device(hostname: str, tenant: Tenant) -> dict:
+    """Returns the inventory entry for a device, if the tenant may act on it."""
+    device = tenant_devices(tenant).get(hostname)
+    if not device:
+        raise HTTPException(status_code=403, detail=f"{hostname} is not in tenant {tenant.name}")
+    return device
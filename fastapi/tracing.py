@@ -0,0 +1,36 @@
+"""OpenTelemetry tracing for the API and the device calls it makes.
+
+Spans are exported over OTLP/gRPC to config.OTLP_ENDPOINT (e.g. a local
+collector on http://127.0.0.1:4317). Incoming requests and httpx calls (e.g.
+eAPI) are instrumented automatically, and the library code creates its own
+spans with the OpenTelemetry API, nested under the request that made them.
+
+Only the API process exports spans. A POST /traceroute request breaks down
+into napalm.open (the SSH login), napalm.traceroute and a dns.ptr per hop
+address that isn't cached yet. The Netmiko and Panorama spans are only
+exported once an API route calls that code; none does yet. The CLI scripts
+don't configure a tracer provider, so their spans are no-ops.
+"""
+
+import config
+from opentelemetry import trace
+from opentelemetry.exporter.otlp.proto.grpc.trace_exporter import OTLPSpanExporter
+from opentelemetry.instrumentation.fastapi import FastAPIInstrumentor
+from opentelemetry.instrumentation.httpx import HTTPXClientInstrumentor
+from opentelemetry.sdk.resources import Resource
+from opentelemetry.sdk.trace import TracerProvider
+from opentelemetry.sdk.trace.export import BatchSpanProcessor
+
+from fastapi import FastAPI
+
+
+def setup_tracing(app: FastAPI):
+    endpoint = getattr(config, "OTLP_ENDPOINT", None)
+    if not endpoint:
+        return
+    provider = TracerProvider(resource=Resource.create({"service.name": "sandbox-api"}))
+    provider.add_span_processor(BatchSpanProcessor(OTLPSpanExporter(endpoint=endpoint)))
+    trace.set_tracer_provider(provider)
+    FastAPIInstrumentor.instrument_app(app)
+    HTTPXClientInstrumentor().instrument()
+
@@ -0,0 +1,145 @@
+"""Assert that traces follow an expected path.
+
+Expected paths are declared in a YAML file (config.PATH_SPECS):
+
+    - name: dc-to-internet
+      destination: 8.8.8.8
+      source: 10.1.1.10          # optional
+      must_traverse: [fw1-dc, 10.255.0.1]
+      must_not_traverse: [wan-backup]
+      max_hops: 12
+      max_rtt: 40                # ms, at the destination
+
+Hops match a waypoint by IP address, PTR name or host (the PTR up to "--").
+
+Usage: python assertions.py [SPEC_NAME ...]
+"""
+
+import sys
+from socket import gethostbyaddr
+
+import config
+import yaml
+from icmplib import is_hostname, resolve, traceroute
+from pydantic import BaseModel
+from rich import print
+from rich.table import Table
+
+
+class PathSpec(BaseModel):
+    name: str
+    destination: str
+    source: str | None = None
+    must_traverse: list[str] = []
+    must_not_traverse: list[str] = []
+    max_hops: int | None = None
+    max_rtt: float | None = None
+
+
+def load_specs(path: str) -> list[PathSpec]:
+    with open(path) as f:
+        return [PathSpec(**spec) for spec in yaml.safe_load(f)]
+
+
+def destination_address(spec: PathSpec) -> str:
+    """The spec's destination as an IP address, resolving a hostname once."""
+    if is_hostname(spec.destination):
+        return resolve(spec.destination)[0]
+    return spec.destination
+
+
+def trace(spec: PathSpec, address: str) -> list[dict]:
+    """Traces to the destination's resolved address, so checks compare the same IP."""
+    hops = []
+    for hop in traceroute(address, source=spec.source):
+        try:
+            interface = gethostbyaddr(hop.address)[0]
+        except OSError:
+            interface = hop.address
+        hops.append(
+            {
+                "distance": hop.distance,
+                "address": hop.address,
+                "interface": interface,
+                "host": interface.split("--")[0],
+                "rtt": hop.avg_rtt,
+            }
+        )
+    return hops
+
+
+def matches(hop: dict, waypoint: str) -> bool:
+    return waypoint in (hop["address"], hop["interface"], hop["host"])
+
+
+def check(spec: PathSpec, hops: list[dict], address: str | None = None) -> list[dict]:
+    """Checks a trace against a spec.
+
+    Args:
+        spec: Expected path.
+        hops: Trace as returned by trace().
+        address: Resolved destination address, if the spec names a host.
+
+    Returns:
+        List of failures, each with a message and the offending hop's
+        distance (None if the failure isn't tied to a single hop).
+    """
+    address = address or spec.destination
+    failures = []
+    for waypoint in spec.must_traverse:
+        if not any(matches(hop, waypoint) for hop in hops):
+            failures.append({"hop": None, "message": f"{waypoint} not traversed"})
+    for waypoint in spec.must_not_traverse:
+        for hop in hops:
+            if matches(hop, waypoint):
+                failures.append({"hop": hop["distance"], "message": f"{waypoint} traversed"})
+    # icmplib leaves out hops that didn't answer, so count by the last hop's TTL
+    if spec.max_hops is not None and hops and hops[-1]["distance"] > spec.max_hops:
+        failures.append(
+            {
+                "hop": hops[-1]["distance"],
+                "message": f"{hops[-1]['distance']} hops > {spec.max_hops}",
+            }
+        )
+    if not hops or hops[-1]["address"] != address:
+        destination = spec.destination
+        if address != spec.destination:
+            destination += f" ({address})"
+        failures.append({"hop": None, "message": f"{destination} not reached"})
+    elif spec.max_rtt is not None and hops[-1]["rtt"] > spec.max_rtt:
+        failures.append(
+            {
+                "hop": hops[-1]["distance"],
+                "message": f"RTT {hops[-1]['rtt']:.1f} ms > {spec.max_rtt} ms",
+            }
+        )
+    return failures
+
+
+def print_result(spec: PathSpec, hops: list[dict], failures: list[dict]):
+    status = "[red]FAIL[/red]" if failures else "[green]PASS[/green]"
+    print(f"{status} {spec.name} ({spec.source or 'local'} -> {spec.destination})")
+    offending = {failure["hop"] for failure in failures}
+    table = Table("Hop", "Address", "Interface", "RTT ms")
+    for hop in hops:
+        style = "bold red" if hop["distance"] in offending else None
+        table.add_row(
+            str(hop["distance"]), hop["address"], hop["interface"], f"{hop['rtt']:.1f}", style=style
+        )
+    print(table)
+    for failure in failures:
+        print(f"  [red]{failure['message']}[/red]")
+
+
+if __name__ == "__main__":
+    specs = load_specs(config.PATH_SPECS)
+    if len(sys.argv) > 1:
+        specs = [spec for spec in specs if spec.name in sys.argv[1:]]
+    failed = False
+    for spec in specs:
+        address = destination_address(spec)
+        hops = trace(spec, address)
+        failures = check(spec, hops, address)
+        print_result(spec, hops, failures)
+        failed = failed or bool(failures)
+    sys.exit(1 if failed else 0)
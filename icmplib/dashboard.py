@@ -0,0 +1,199 @@
+"""Full-screen dashboard of monitored paths.
+
+Checks are configured in config.py:
+
+    CHECKS = [{"name": "dns", "destination": "8.8.8.8"}, ...]
+    CHECK_INTERVAL = 30
+
+Each check is pinged every interval and traced to spot path changes. Move
+with the up/down arrow keys and press Enter to drill down into a check's
+latest traceroute, Esc or Backspace to go back, q to quit.
+"""
+
+import os
+import select
+import sys
+import termios
+import threading
+import tty
+from collections import deque
+from dataclasses import dataclass, field
+from datetime import datetime
+from socket import gethostbyaddr
+
+import config
+from icmplib import ping, traceroute
+from rich.console import Group
+from rich.live import Live
+from rich.table import Table
+from rich.text import Text
+
+SPARK = "▁▂▃▄▅▆▇█"
+INTERVAL = getattr(config, "CHECK_INTERVAL", 30)
+
+
+@dataclass
+class CheckState:
+    name: str
+    destination: str
+    status: str = "pending"
+    loss: float | None = None
+    rtts: deque = field(default_factory=lambda: deque(maxlen=40))
+    hops: list[dict] = field(default_factory=list)
+    last_run: datetime | None = None
+    last_path_change: datetime | None = None
+    # rtts is appended to by the monitor thread while the UI thread draws it
+    lock: threading.Lock = field(default_factory=threading.Lock, repr=False)
+
+    def rtt_history(self) -> list[float | None]:
+        with self.lock:
+            return list(self.rtts)
+
+
+def sparkline(values) -> str:
+    values = [v for v in values if v is not None]
+    if not values:
+        return ""
+    low, high = min(values), max(values)
+    span = (high - low) or 1
+    return "".join(SPARK[int((v - low) / span * (len(SPARK) - 1))] for v in values)
+
+
+def enrich(address: str) -> tuple[str, str]:
+    """Resolves a hop to (host, interface) from its PTR, as in traceroute.py."""
+    try:
+        interface = gethostbyaddr(address)[0]
+    except OSError:
+        return address, ""
+    return interface.split("--")[0], interface
+
+
+def run_check(state: CheckState):
+    host = ping(state.destination, count=4, interval=0.2)
+    state.loss = host.packet_loss
+    with state.lock:
+        state.rtts.append(host.avg_rtt if host.is_alive else None)
+    state.status = "up" if host.packet_loss == 0 else "degraded" if host.is_alive else "down"
+
+    hops = []
+    for hop in traceroute(state.destination):
+        name, interface = enrich(hop.address)
+        hops.append(
+            {
+                "distance": hop.distance,
+                "address": hop.address,
+                "host": name,
+                "interface": interface,
+                "rtt": hop.avg_rtt,
+                "loss": hop.packet_loss,
+            }
+        )
+    previous = [hop["address"] for hop in state.hops]
+    if previous and previous != [hop["address"] for hop in hops]:
+        state.last_path_change = datetime.now()
+    state.hops = hops
+    state.last_run = datetime.now()
+
+
+def monitor(states: list[CheckState], stop: threading.Event):
+    while not stop.is_set():
+        for state in states:
+            try:
+                run_check(state)
+            except Exception as e:
+                state.status = f"error: {e}"
+        stop.wait(INTERVAL)
+
+
+def overview(states: list[CheckState], cursor: int = 0) -> Table:
+    table = Table(title="Monitored paths", expand=True)
+    columns = ("#", "Check", "Destination", "Status", "Latency", "RTT ms", "Loss", "Path change")
+    for column in columns:
+        table.add_column(column)
+    colors = {"up": "green", "degraded": "yellow", "down": "red"}
+    for i, state in enumerate(states, start=1):
+        rtts = state.rtt_history()
+        latest = rtts[-1] if rtts else None
+        table.add_row(
+            str(i),
+            state.name,
+            state.destination,
+            Text(state.status, style=colors.get(state.status, "magenta")),
+            sparkline(rtts),
+            f"{latest:.1f}" if latest is not None else "-",
+            f"{state.loss:.0%}" if state.loss is not None else "-",
+            state.last_path_change.strftime("%Y-%m-%d %H:%M:%S") if state.last_path_change else "-",
+            style="reverse" if i - 1 == cursor else None,
+        )
+    return table
+
+
+def detail(state: CheckState) -> Group:
+    table = Table(title=f"{state.name}: traceroute to {state.destination}", expand=True)
+    for column in ("Hop", "Address", "Host", "Interface", "RTT ms", "Loss"):
+        table.add_column(column)
+    for hop in state.hops:
+        table.add_row(
+            str(hop["distance"]),
+            hop["address"],
+            hop["host"],
+            hop["interface"],
+            f"{hop['rtt']:.1f}",
+            f"{hop['loss']:.0%}",
+        )
+    last_run = state.last_run.strftime("%H:%M:%S") if state.last_run else "never"
+    return Group(table, Text(f"Last run {last_run}. Press Esc to go back."))
+
+
+KEYS = {
+    "\x1b[A": "up",
+    "\x1b[B": "down",
+    "\n": "enter",
+    "\r": "enter",
+    "\x1b": "back",
+    "\x7f": "back",
+}
+
+
+def read_key(timeout: float) -> str | None:
+    """Reads a key press, naming arrow keys, Enter, Esc and Backspace as in KEYS."""
+    ready, _, _ = select.select([sys.stdin], [], [], timeout)
+    if not ready:
+        return None
+    # Read unbuffered, so an arrow key's escape sequence arrives in one piece
+    key = os.read(sys.stdin.fileno(), 8).decode(errors="ignore")
+    return KEYS.get(key, key)
+
+
+def main():
+    states = [CheckState(check["name"], check["destination"]) for check in config.CHECKS]
+    stop = threading.Event()
+    threading.Thread(target=monitor, args=(states, stop), daemon=True).start()
+
+    selected = None
+    cursor = 0
+    old_settings = termios.tcgetattr(sys.stdin)
+    try:
+        tty.setcbreak(sys.stdin.fileno())
+        with Live(overview(states), screen=True, auto_refresh=False) as live:
+            while True:
+                key = read_key(0.5)
+                if key == "q":
+                    break
+                if key == "back":
+                    selected = None
+                elif key == "up" and not selected:
+                    cursor = max(cursor - 1, 0)
+                elif key == "down" and not selected:
+                    cursor = min(cursor + 1, len(states) - 1)
+                elif key == "enter" and states:
+                    selected = states[cursor]
+                view = detail(selected) if selected else overview(states, cursor)
+                live.update(view, refresh=True)
+    finally:
+        stop.set()
+        termios.tcsetattr(sys.stdin, termios.TCSADRAIN, old_settings)
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,287 @@
+"""Export ping and traceroute measurements as time series.
+
+Measurements are collected as points (measurement, tags, fields, timestamp)
+and rendered as InfluxDB line protocol or OpenMetrics text, then written to a
+file, posted to an InfluxDB v2 compatible /api/v2/write endpoint or served
+for scraping on /metrics.
+
+NAPALM traces are read from the history file napalm/traceroute.py records
+(HISTORY_FILE there). Re-exporting the same trace writes the same points, so
+InfluxDB overwrites rather than duplicates them.
+
+    python export.py [--napalm-history FILE] [--serve PORT]
+
+Run receiver.py to try the write and scrape paths against a local stand-in.
+"""
+
+import argparse
+import json
+import socket
+import threading
+import time
+from datetime import datetime
+from http.server import BaseHTTPRequestHandler, ThreadingHTTPServer
+
+import config
+import httpx
+from icmplib import Hop, Host, ping, traceroute
+from rich import print
+
+# Seconds between measurements when serving /metrics
+MEASURE_INTERVAL = getattr(config, "MEASURE_INTERVAL", 60)
+
+
+def point(measurement: str, tags: dict, fields: dict, timestamp: float | None = None) -> dict:
+    return {
+        "measurement": measurement,
+        "tags": {k: str(v) for k, v in tags.items() if v is not None},
+        "fields": fields,
+        "timestamp": timestamp or time.time(),
+    }
+
+
+def ping_points(host: Host, source: str, device: str | None = None) -> list[dict]:
+    """Points for an icmplib ping result."""
+    tags = {"source": source, "destination": host.address, "device": device}
+    return [
+        point(
+            "ping",
+            tags,
+            {
+                "rtt_min": host.min_rtt,
+                "rtt_avg": host.avg_rtt,
+                "rtt_max": host.max_rtt,
+                "loss": host.packet_loss,
+                "packets_sent": host.packets_sent,
+            },
+        )
+    ]
+
+
+def traceroute_points(
+    hops: list[Hop], source: str, destination: str, device: str | None = None
+) -> list[dict]:
+    """Points for an icmplib traceroute result, one per hop."""
+    timestamp = time.time()
+    return [
+        point(
+            "traceroute_hop",
+            {
+                "source": source,
+                "destination": destination,
+                "hop": hop.distance,
+                "hop_address": hop.address,
+                "device": device,
+            },
+            {
+                "rtt_min": hop.min_rtt,
+                "rtt_avg": hop.avg_rtt,
+                "rtt_max": hop.max_rtt,
+                "loss": hop.packet_loss,
+            },
+            timestamp,
+        )
+        for hop in hops
+    ]
+
+
+def napalm_traceroute_points(
+    results: dict,
+    source: str,
+    destination: str,
+    device: str | None = None,
+    timestamp: float | None = None,
+) -> list[dict]:
+    """Points for a NAPALM traceroute results dict, one per hop.
+
+    Probes without an address (timeouts) count towards loss.
+    """
+    if results.get("error"):
+        return []
+    timestamp = timestamp or time.time()
+    points = []
+    for distance, hop in sorted(results["success"].items(), key=lambda h: int(h[0])):
+        probes = list(hop["probes"].values())
+        answered = [p for p in probes if p.get("ip_address") and p["ip_address"] != "*"]
+        rtts = [p["rtt"] for p in answered]
+        fields = {"loss": 1 - len(answered) / len(probes) if probes else 1.0}
+        if rtts:
+            fields.update(rtt_min=min(rtts), rtt_avg=sum(rtts) / len(rtts), rtt_max=max(rtts))
+        points.append(
+            point(
+                "traceroute_hop",
+                {
+                    "source": source,
+                    "destination": destination,
+                    "hop": int(distance),
+                    "hop_address": answered[0]["ip_address"] if answered else None,
+                    "device": device,
+                },
+                fields,
+                timestamp,
+            )
+        )
+    return points
+
+
+def history_points(history_file: str, latest: bool = False) -> list[dict]:
+    """Points for the NAPALM traces in a napalm/traceroute.py history file.
+
+    The device tag is the trace's source, i.e. the device it ran on.
+
+    Args:
+        history_file: Path to the JSON lines history file.
+        latest: Only the latest trace of each source and destination, e.g. for
+            a scrape, where each series may appear once.
+    """
+    entries = {}
+    with open(history_file) as f:
+        for number, line in enumerate(f):
+            if not line.strip():
+                continue
+            entry = json.loads(line)
+            entries[(entry["source"], entry["destination"]) if latest else number] = entry
+    points = []
+    for entry in entries.values():
+        points += napalm_traceroute_points(
+            entry["results"],
+            entry["source"],
+            entry["destination"],
+            device=entry["source"],
+            timestamp=datetime.fromisoformat(entry["timestamp"]).timestamp(),
+        )
+    return points
+
+
+def _escape_tag(value: str) -> str:
+    return value.replace("\\", "\\\\").replace(",", "\\,").replace("=", "\\=").replace(" ", "\\ ")
+
+
+def _field_value(value) -> str:
+    if isinstance(value, bool):
+        return "true" if value else "false"
+    if isinstance(value, int):
+        return f"{value}i"
+    if isinstance(value, float):
+        return repr(value)
+    return '"' + str(value).replace("\\", "\\\\").replace('"', '\\"') + '"'
+
+
+def _escape_label(value: str) -> str:
+    return value.replace("\\", "\\\\").replace('"', '\\"').replace("\n", "\\n")
+
+
+def to_line_protocol(points: list[dict]) -> str:
+    """Renders points as InfluxDB line protocol with nanosecond timestamps."""
+    lines = []
+    for p in points:
+        tags = "".join(
+            f",{_escape_tag(k)}={_escape_tag(v)}" for k, v in sorted(p["tags"].items())
+        )
+        fields = ",".join(
+            f"{_escape_tag(k)}={_field_value(v)}" for k, v in p["fields"].items() if v is not None
+        )
+        lines.append(f"{p['measurement']}{tags} {fields} {int(p['timestamp'] * 1e9)}")
+    return "\n".join(lines) + "\n"
+
+
+def to_openmetrics(points: list[dict]) -> str:
+    """Renders points as OpenMetrics gauges named <measurement>_<field>."""
+    families = {}
+    for p in points:
+        labels = ",".join(f'{k}="{_escape_label(v)}"' for k, v in sorted(p["tags"].items()))
+        for field, value in p["fields"].items():
+            if value is None:
+                continue
+            families.setdefault(f"{p['measurement']}_{field}", []).append(
+                f"{p['measurement']}_{field}{{{labels}}} {float(value)} {p['timestamp']:.3f}"
+            )
+    lines = []
+    for name, samples in families.items():
+        lines.append(f"# TYPE {name} gauge")
+        lines.extend(samples)
+    lines.append("# EOF")
+    return "\n".join(lines) + "\n"
+
+
+def write_file(path: str, text: str, append: bool = True):
+    with open(path, "a" if append else "w") as f:
+        f.write(text)
+
+
+def write_influx(url: str, org: str, bucket: str, token: str, points: list[dict]):
+    """Posts points to an InfluxDB v2 /api/v2/write endpoint."""
+    resp = httpx.post(
+        f"{url.rstrip('/')}/api/v2/write",
+        params={"org": org, "bucket": bucket, "precision": "ns"},
+        headers={"Authorization": f"Token {token}"},
+        content=to_line_protocol(points),
+    )
+    resp.raise_for_status()
+
+
+def measure(napalm_history: str | None = None, latest: bool = False) -> list[dict]:
+    """Pings and traces config.TRACE_DEST, plus NAPALM traces from history, if given."""
+    source = getattr(config, "SOURCE", None) or socket.gethostname()
+    points = ping_points(ping(config.TRACE_DEST, count=4), source)
+    points += traceroute_points(traceroute(config.TRACE_DEST), source, config.TRACE_DEST)
+    if napalm_history:
+        points += history_points(napalm_history, latest)
+    return points
+
+
+OPENMETRICS_CONTENT_TYPE = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+
+def serve(port: int, napalm_history: str | None = None):
+    """Serves the latest measurements on /metrics.
+
+    Measuring takes a ping and a full traceroute, often longer than a scrape
+    timeout, so it runs every MEASURE_INTERVAL seconds in the background and
+    scrapes get the latest result (503 until the first one is done).
+    """
+    latest = {"body": None}
+
+    def measure_forever():
+        while True:
+            try:
+                latest["body"] = to_openmetrics(measure(napalm_history, latest=True)).encode()
+            except Exception as e:
+                print(f"[red]Measurement failed: {e}[/red]")
+            time.sleep(MEASURE_INTERVAL)
+
+    class MetricsHandler(BaseHTTPRequestHandler):
+        def do_GET(self):
+            if self.path != "/metrics":
+                self.send_error(404)
+                return
+            body = latest["body"]
+            if body is None:
+                self.send_error(503, "No measurements yet")
+                return
+            self.send_response(200)
+            self.send_header("Content-Type", OPENMETRICS_CONTENT_TYPE)
+            self.send_header("Content-Length", str(len(body)))
+            self.end_headers()
+            self.wfile.write(body)
+
+    threading.Thread(target=measure_forever, daemon=True).start()
+    ThreadingHTTPServer(("0.0.0.0", port), MetricsHandler).serve_forever()
+
+
+if __name__ == "__main__":
+    parser = argparse.ArgumentParser(description=__doc__.splitlines()[0])
+    parser.add_argument("--napalm-history", help="napalm/traceroute.py history file to include")
+    parser.add_argument("--serve", type=int, metavar="PORT", help="Serve /metrics on PORT")
+    args = parser.parse_args()
+
+    if args.serve:
+        serve(args.serve, args.napalm_history)
+    points = measure(args.napalm_history)
+    print(to_line_protocol(points))
+    if getattr(config, "METRICS_FILE", None):
+        write_file(config.METRICS_FILE, to_openmetrics(points), append=False)
+    if getattr(config, "INFLUX_URL", None):
+        write_influx(
+            config.INFLUX_URL, config.INFLUX_ORG, config.INFLUX_BUCKET, config.INFLUX_TOKEN, points
+        )
@@ -0,0 +1,71 @@
+""" Local stand-ins for the systems export.py feeds, to test it without them.
+
+Write path: set INFLUX_URL = "http://127.0.0.1:8086" in config.py, run
+"python receiver.py", then export.py.
+
+Scrape path: run "python export.py --serve 9101", then
+"python receiver.py --scrape http://127.0.0.1:9101/metrics" to fetch and check
+the exposition the way Prometheus would.
+"""
+
+import argparse
+import re
+from http.server import BaseHTTPRequestHandler, HTTPServer
+from urllib.parse import parse_qs, urlparse
+
+import httpx
+
+# name{labels} value [timestamp]
+SAMPLE_PATTERN = re.compile(r'^[a-zA-Z_:][\w:]*(\{(\w+="(\\.|[^"\\])*",?)*\})? \S+( \S+)?$')
+
+
+class WriteHandler(BaseHTTPRequestHandler):
+    def do_POST(self):
+        url = urlparse(self.path)
+        if url.path != "/api/v2/write":
+            self.send_error(404)
+            return
+        body = self.rfile.read(int(self.headers.get("Content-Length", 0))).decode()
+        print(f"{parse_qs(url.query)} {self.headers.get('Authorization')}")
+        for line in body.splitlines():
+            print(f"  {line}")
+        self.send_response(204)
+        self.end_headers()
+
+
+def check_exposition(content_type: str, text: str) -> list[str]:
+    """Lists problems with an OpenMetrics exposition; empty if it's valid."""
+    problems = []
+    if not content_type.startswith("application/openmetrics-text"):
+        problems.append(f"content type is {content_type}")
+    lines = text.rstrip("\n").splitlines()
+    if not lines or lines[-1] != "# EOF":
+        problems.append("missing # EOF terminator")
+    for number, line in enumerate(lines, 1):
+        if line.startswith("#"):
+            continue
+        if not SAMPLE_PATTERN.match(line):
+            problems.append(f"line {number} is not a sample: {line}")
+    return problems
+
+
+def scrape(url: str):
+    resp = httpx.get(url, headers={"Accept": "application/openmetrics-text"}, timeout=10)
+    resp.raise_for_status()
+    print(resp.text)
+    problems = check_exposition(resp.headers.get("Content-Type", ""), resp.text)
+    for problem in problems:
+        print(f"INVALID: {problem}")
+    if not problems:
+        print("Exposition OK")
+
+
+if __name__ == "__main__":
+    parser = argparse.ArgumentParser(description=__doc__.splitlines()[0])
+    parser.add_argument("--scrape", metavar="URL", help="Scrape and check a /metrics endpoint")
+    args = parser.parse_args()
+
+    if args.scrape:
+        scrape(args.scrape)
+    else:
+        HTTPServer(("127.0.0.1", 8086), WriteHandler).serve_forever()
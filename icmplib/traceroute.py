@@ -1,9 +1,25 @@
+import sys
+from pathlib import Path
+
 from icmplib import traceroute, Hop
 import config
 from socket import gethostbyaddr
 from rich import print
 
-trace = traceroute(config.TRACE_DEST)
+# The DSCP range check and TOS conversion are shared with netmiko/eos_backends.py
+sys.path.append(str(Path(__file__).resolve().parent.parent / "netmiko"))
+from eos_backends import dscp_to_tos  # noqa: E402
+
+DSCP = getattr(config, "DSCP", None)
+PAYLOAD_SIZE = getattr(config, "PAYLOAD_SIZE", None)
+
+options = {}
+if DSCP is not None:
+    options["traffic_class"] = dscp_to_tos(DSCP)
+if PAYLOAD_SIZE is not None:
+    options["payload_size"] = PAYLOAD_SIZE
+
+trace = traceroute(config.TRACE_DEST, **options)
 hosts = []
 for hop in trace:
     hop: Hop = hop
@@ -12,4 +28,4 @@ for hop in trace:
     interface = gethostbyaddr(hop.address)[0]
     host = interface.split("--")[0]
     hosts.append((host, interface, hop.address))
-print(hosts)
+print({"parameters": {"dscp": DSCP, "payload_size": PAYLOAD_SIZE}, "hops": hosts})
@@ -0,0 +1,182 @@
+"""Parse IOS and EOS configs into a parent/child tree.
+
+NAPALM get_config() returns the config as flat text. Both IOS and EOS nest
+child lines under their parent by indentation, so the tree is built from
+that, skipping "!" comments and the trailing "end".
+
+    tree = ConfigTree.parse(device.get_config()["running"])
+    tree.children_of(r"^router bgp 65000")
+    tree.interfaces_with_acl("MGMT-IN")
+    tree.diff(other_tree)
+"""
+
+import difflib
+import re
+from dataclasses import dataclass, field
+
+# Multi-line banners are kept verbatim until the closing delimiter: IOS
+# repeats the delimiter after "banner motd", EOS ends the banner with "EOF"
+BANNER_PATTERN = re.compile(r"^banner \S+(?: (\^C|\S))?")
+EOS_BANNER_END = "EOF"
+
+# Blocks whose entries are evaluated in order, e.g. ACL entries without
+# sequence numbers, and top-level lines evaluated in order among lines with
+# the same key, e.g. numbered ACLs, IOS prefix-lists and route-map entries
+ORDERED_BLOCKS = re.compile(r"^(ip|ipv6|mac) access-list |^(ip|ipv6) prefix-list \S+$")
+ORDERED_LINES = re.compile(r"^(access-list \S+|(ip|ipv6) prefix-list \S+|route-map \S+) ")
+
+
+@dataclass
+class ConfigLine:
+    text: str
+    parent: "ConfigLine | None" = field(default=None, repr=False, compare=False)
+    children: list["ConfigLine"] = field(default_factory=list)
+
+    @property
+    def path(self) -> tuple[str, ...]:
+        """Texts of this line and its parents, outermost first."""
+        line, path = self, []
+        while line and line.parent:
+            path.insert(0, line.text)
+            line = line.parent
+        return tuple(path)
+
+    def find_children(self, pattern: str) -> list["ConfigLine"]:
+        return [child for child in self.children if re.search(pattern, child.text)]
+
+    def walk(self):
+        for child in self.children:
+            yield child
+            yield from child.walk()
+
+
+class ConfigTree(ConfigLine):
+    def __init__(self):
+        super().__init__(text="")
+
+    @classmethod
+    def parse(cls, config: str) -> "ConfigTree":
+        tree = cls()
+        # (indent, line) of the current line and its parents
+        stack: list[tuple[int, ConfigLine]] = [(-1, tree)]
+        lines = iter(config.splitlines())
+        for raw in lines:
+            text = raw.strip()
+            if not text or text.startswith("!") or text == "end":
+                continue
+            indent = len(raw) - len(raw.lstrip(" "))
+            while stack[-1][0] >= indent:
+                stack.pop()
+            parent = stack[-1][1]
+            line = ConfigLine(text=text, parent=parent)
+            parent.children.append(line)
+            stack.append((indent, line))
+
+            if banner := BANNER_PATTERN.match(text):
+                delimiter = banner.group(1)
+                if delimiter and text.count(delimiter) > 1:
+                    continue
+                for body in lines:
+                    line.children.append(ConfigLine(text=body, parent=line))
+                    if delimiter in body if delimiter else body.strip() == EOS_BANNER_END:
+                        break
+        return tree
+
+    def find(self, pattern: str) -> list[ConfigLine]:
+        """All lines, at any depth, matching a regex."""
+        return [line for line in self.walk() if re.search(pattern, line.text)]
+
+    def children_of(self, parent: str) -> list[ConfigLine]:
+        """Children of the top-level line(s) matching a regex, e.g. r"^router bgp"."""
+        return [child for line in self.find_children(parent) for child in line.children]
+
+    def find_with_child(self, parent: str, child: str) -> list[ConfigLine]:
+        """Top-level lines matching parent that have a child matching child."""
+        return [line for line in self.find_children(parent) if line.find_children(child)]
+
+    def interfaces_with_acl(self, acl: str, direction: str | None = None) -> list[str]:
+        """Names of interfaces with an ACL applied, optionally in one direction."""
+        child = rf"^ip(v6)? (access-group|traffic-filter) {re.escape(acl)}"
+        child += rf" {direction}$" if direction else r"(\s|$)"
+        return [
+            line.text.split(maxsplit=1)[1]
+            for line in self.find_with_child(r"^interface ", child)
+        ]
+
+    def to_text(self, indent: str = " ") -> str:
+        return "\n".join(indent * (len(line.path) - 1) + line.text for line in self.walk())
+
+    def diff(self, other: "ConfigTree") -> list[tuple[str, tuple[str, ...]]]:
+        """Semantic diff against another tree.
+
+        Lines are compared by text under the same parent, ignoring order,
+        except where order changes behaviour: entries of ACLs and prefix-lists,
+        and top-level numbered ACL, prefix-list and route-map lines with the
+        same name. Those are compared as sequences, so a reordered entry shows
+        as removed and added. A line only in one tree is reported once,
+        without its children.
+
+        Returns:
+            List of ("-", path) for lines only in this tree and ("+", path)
+            for lines only in the other.
+        """
+        return _diff(self, other)
+
+
+def _diff(old: ConfigLine, new: ConfigLine) -> list[tuple[str, tuple[str, ...]]]:
+    if ORDERED_BLOCKS.match(old.text):
+        return _diff_ordered(old.children, new.children)
+    old_ordered, old_rest = _split_ordered(old.children)
+    new_ordered, new_rest = _split_ordered(new.children)
+    changes = _diff_unordered(old_rest, new_rest)
+    for key in old_ordered | new_ordered:
+        changes.extend(_diff_ordered(old_ordered.get(key, []), new_ordered.get(key, [])))
+    return changes
+
+
+def _split_ordered(lines: list[ConfigLine]) -> tuple[dict[str, list[ConfigLine]], list]:
+    """Splits lines into ORDERED_LINES groups by key, and the rest."""
+    ordered, rest = {}, []
+    for line in lines:
+        if match := ORDERED_LINES.match(line.text):
+            ordered.setdefault(match.group(1), []).append(line)
+        else:
+            rest.append(line)
+    return ordered, rest
+
+
+def _diff_ordered(
+    old: list[ConfigLine], new: list[ConfigLine]
+) -> list[tuple[str, tuple[str, ...]]]:
+    matcher = difflib.SequenceMatcher(
+        a=[line.text for line in old], b=[line.text for line in new], autojunk=False
+    )
+    changes = []
+    for tag, i1, i2, j1, j2 in matcher.get_opcodes():
+        if tag == "equal":
+            for old_line, new_line in zip(old[i1:i2], new[j1:j2]):
+                changes.extend(_diff(old_line, new_line))
+            continue
+        changes.extend(("-", line.path) for line in old[i1:i2])
+        changes.extend(("+", line.path) for line in new[j1:j2])
+    return changes
+
+
+def _diff_unordered(
+    old: list[ConfigLine], new: list[ConfigLine]
+) -> list[tuple[str, tuple[str, ...]]]:
+    # Repeated sibling lines are paired up in order, so duplicates still count
+    old_children, new_children = {}, {}
+    for line in old:
+        old_children.setdefault(line.text, []).append(line)
+    for line in new:
+        new_children.setdefault(line.text, []).append(line)
+    changes = []
+    for text, lines in old_children.items():
+        changes.extend(("-", line.path) for line in lines[len(new_children.get(text, [])) :])
+    for text, lines in new_children.items():
+        matched = old_children.get(text, [])
+        changes.extend(("+", line.path) for line in lines[len(matched) :])
+        for old_line, new_line in zip(matched, lines):
+            changes.extend(_diff(old_line, new_line))
+    return changes
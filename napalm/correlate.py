@@ -0,0 +1,164 @@
+"""Correlate traceroute path changes with configuration changes.
+
+For each path change found in the traceroute history, looks at the devices on
+the old and new paths and lists candidate causes:
+
+- Config backups that changed between the last good trace and the new one,
+  read from BACKUP_DIR/<device>/<timestamp>.cfg (timestamp in ISO 8601).
+- Device commands from the audit log (JSON lines with timestamp, device,
+  user and command keys) within WINDOW seconds of the change.
+
+Timestamps without a UTC offset, in backup names or the audit log, are taken
+as UTC.
+"""
+
+import difflib
+import json
+from datetime import datetime, timedelta, timezone
+from pathlib import Path
+
+import config
+from history import load_history, path_changes
+from rich import print
+from rich.syntax import Syntax
+
+WINDOW = getattr(config, "CORRELATION_WINDOW", 900)
+
+
+def parse_timestamp(value: str) -> datetime:
+    """Parses an ISO 8601 timestamp as an aware datetime in UTC."""
+    when = datetime.fromisoformat(value)
+    if when.tzinfo is None:
+        return when.replace(tzinfo=timezone.utc)
+    return when.astimezone(timezone.utc)
+
+
+def device_name(hop: dict, devices: dict[str, str] | None = None) -> str | None:
+    """Maps a hop to a device name.
+
+    Uses the devices mapping (IP address -> name) if given, otherwise the
+    resolved host name up to the "--" interface separator.
+    """
+    if not hop["ip_address"]:
+        return None
+    if devices and hop["ip_address"] in devices:
+        return devices[hop["ip_address"]]
+    host_name = hop["host_name"] or hop["ip_address"]
+    return host_name.split("--")[0]
+
+
+def load_backups(backup_dir: str | Path, device: str) -> list[tuple[datetime, Path]]:
+    """Lists config backups for a device, oldest first."""
+    device_dir = Path(backup_dir) / device
+    if not device_dir.is_dir():
+        return []
+    backups = []
+    for backup in device_dir.glob("*.cfg"):
+        try:
+            backups.append((parse_timestamp(backup.stem), backup))
+        except ValueError:
+            continue
+    return sorted(backups)
+
+
+def backup_at(backups: list[tuple[datetime, Path]], when: datetime) -> Path | None:
+    """Returns the latest backup taken at or before a point in time."""
+    candidates = [backup for taken, backup in backups if taken <= when]
+    return candidates[-1] if candidates else None
+
+
+def config_diff(backup_dir: str | Path, device: str, start: datetime, end: datetime) -> str:
+    """Unified diff of a device's config between two points in time, if it changed."""
+    backups = load_backups(backup_dir, device)
+    before = backup_at(backups, start)
+    after = backup_at(backups, end)
+    if not before or not after or before == after:
+        return ""
+    return "".join(
+        difflib.unified_diff(
+            before.read_text().splitlines(keepends=True),
+            after.read_text().splitlines(keepends=True),
+            fromfile=str(before),
+            tofile=str(after),
+        )
+    )
+
+
+def audit_entries(
+    audit_log: str | Path, devices: set[str], start: datetime, end: datetime
+) -> list[dict]:
+    """Commands run against the given devices between start and end."""
+    if not Path(audit_log).exists():
+        return []
+    entries = []
+    with open(audit_log) as f:
+        for line in f:
+            if not line.strip():
+                continue
+            entry = json.loads(line)
+            when = parse_timestamp(entry["timestamp"])
+            if entry["device"] in devices and start <= when <= end:
+                entries.append(entry)
+    return entries
+
+
+def correlate(
+    change: dict,
+    backup_dir: str | Path,
+    audit_log: str | Path,
+    window: int = WINDOW,
+    devices: dict[str, str] | None = None,
+) -> dict:
+    """Lists candidate causes for a single path change.
+
+    Args:
+        change: Path change as returned by history.path_changes().
+        backup_dir: Directory of per-device config backups.
+        audit_log: Path to the device command audit log.
+        window: Seconds to look around the change.
+        devices: Optional IP address to device name mapping.
+
+    Returns:
+        The change, with a candidates list of devices whose config changed or
+        had commands run against them, including diffs and audit entries.
+    """
+    start = parse_timestamp(change["previous_timestamp"])
+    end = parse_timestamp(change["timestamp"])
+    on_path = {
+        name
+        for hop in change["old_path"] + change["new_path"]
+        if (name := device_name(hop, devices))
+    }
+    window = timedelta(seconds=window)
+    commands = audit_entries(audit_log, on_path, start - window, end + window)
+
+    candidates = []
+    for device in sorted(on_path):
+        diff = config_diff(backup_dir, device, start, end + window)
+        device_commands = [entry for entry in commands if entry["device"] == device]
+        if diff or device_commands:
+            candidates.append({"device": device, "diff": diff, "commands": device_commands})
+    return {**change, "candidates": candidates}
+
+
+def print_correlation(correlation: dict):
+    print(
+        f"[bold]Path change {correlation['source']} -> {correlation['destination']}[/bold] "
+        f"between {correlation['previous_timestamp']} and {correlation['timestamp']}"
+    )
+    print("  old:", " ".join(hop["host_name"] or "*" for hop in correlation["old_path"]))
+    print("  new:", " ".join(hop["host_name"] or "*" for hop in correlation["new_path"]))
+    if not correlation["candidates"]:
+        print("  [yellow]No config changes found on path devices[/yellow]")
+    for candidate in correlation["candidates"]:
+        print(f"  [bold]{candidate['device']}[/bold]")
+        for entry in candidate["commands"]:
+            print(f"    {entry['timestamp']} {entry['user']}: {entry['command']}")
+        if candidate["diff"]:
+            print(Syntax(candidate["diff"], "diff"))
+
+
+if __name__ == "__main__":
+    history = load_history(config.HISTORY_FILE, source=config.SOURCE, destination=config.DEST)
+    for change in path_changes(history):
+        print_correlation(correlate(change, config.BACKUP_DIR, config.AUDIT_LOG))
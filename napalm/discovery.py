@@ -0,0 +1,232 @@
+"""Discover devices on management subnets and propose inventory entries.
+
+Scans config.SUBNETS for hosts listening on SSH, logs in with each of
+config.CREDENTIAL_GROUPS and identifies the platform with NAPALM get_facts().
+Facts are cached in config.FACTS_CACHE and refreshed once older than
+config.FACTS_MAX_AGE seconds.
+
+Proposed entries are written to config.INVENTORY_PROPOSALS in the same shape
+as the inventory file (config.INVENTORY), for review before merging:
+
+    devices:
+      rtr1:
+        host: 10.0.0.1
+        platform: ios
+        credential_group: core
+        groups: []
+        vendor: Cisco
+        ...
+
+Usage: python discovery.py [--interval SECONDS]
+"""
+
+import argparse
+import ipaddress
+import json
+import socket
+import time
+from concurrent.futures import ThreadPoolExecutor
+from datetime import datetime, timezone
+from pathlib import Path
+
+import config
+import paramiko
+import yaml
+from rich import print
+
+from napalm import get_network_driver
+
+DRIVERS = getattr(config, "DRIVERS", ["ios", "eos", "junos", "nxos_ssh"])
+FACTS_MAX_AGE = getattr(config, "FACTS_MAX_AGE", 24 * 60 * 60)
+# SSH banner substrings that hint at the platform, to try the likely driver first
+BANNER_HINTS = {"Cisco": ["ios", "nxos_ssh"], "OpenSSH": ["eos", "junos"]}
+
+
+def ssh_banner(host: str, timeout: float = 1.0) -> str | None:
+    """Returns the SSH banner of a host, or None if port 22 is closed.
+
+    A host that accepts the connection but is slow to send its banner is
+    still open, and gets an empty banner.
+    """
+    try:
+        sock = socket.create_connection((host, 22), timeout=timeout)
+    except OSError:
+        return None
+    with sock:
+        try:
+            return sock.recv(256).decode(errors="replace").strip()
+        except OSError:
+            return ""
+
+
+def can_log_in(host: str, credentials: dict, timeout: float = 10.0) -> bool:
+    """Whether the credentials log in over SSH, with a single attempt."""
+    client = paramiko.SSHClient()
+    client.set_missing_host_key_policy(paramiko.AutoAddPolicy())
+    try:
+        client.connect(
+            host,
+            username=credentials["username"],
+            password=credentials["password"],
+            timeout=timeout,
+            look_for_keys=False,
+            allow_agent=False,
+        )
+    except (paramiko.SSHException, OSError):
+        return False
+    finally:
+        client.close()
+    return True
+
+
+def scan(subnets: list[str], workers: int = 64) -> dict[str, str]:
+    """Finds hosts listening on SSH.
+
+    Returns:
+        Dict of host IP address to SSH banner.
+    """
+    hosts = [str(ip) for subnet in subnets for ip in ipaddress.ip_network(subnet).hosts()]
+    with ThreadPoolExecutor(max_workers=workers) as pool:
+        banners = pool.map(ssh_banner, hosts)
+    return {host: banner for host, banner in zip(hosts, banners) if banner is not None}
+
+
+def drivers_for(banner: str) -> list[str]:
+    for hint, drivers in BANNER_HINTS.items():
+        if hint in banner:
+            return drivers + [d for d in DRIVERS if d not in drivers]
+    return DRIVERS
+
+
+def identify(host: str, banner: str, credential_groups: dict[str, dict]) -> dict | None:
+    """Logs in to a host and collects its facts.
+
+    Checks each credential group with a single login first, so a wrong
+    password costs one failed login per group rather than one per driver.
+    The first group that logs in is tried with every driver until
+    get_facts() succeeds.
+
+    Returns:
+        Dict with facts, driver and credential group, or None if no
+        combination worked.
+    """
+    for group, credentials in credential_groups.items():
+        if not can_log_in(host, credentials):
+            continue
+        for platform in drivers_for(banner):
+            driver = get_network_driver(platform)
+            try:
+                with driver(host, credentials["username"], credentials["password"]) as device:
+                    facts = device.get_facts()
+            except Exception:
+                continue
+            return {
+                "platform": platform,
+                "credential_group": group,
+                "facts": facts,
+                "updated": datetime.now(timezone.utc).isoformat(),
+            }
+        # The credentials work, so another group won't find a driver either
+        return None
+    return None
+
+
+def load_cache(path: str | Path) -> dict:
+    if not Path(path).exists():
+        return {}
+    with open(path) as f:
+        return json.load(f)
+
+
+def is_fresh(entry: dict, max_age: int = FACTS_MAX_AGE) -> bool:
+    updated = datetime.fromisoformat(entry["updated"])
+    return (datetime.now(timezone.utc) - updated).total_seconds() < max_age
+
+
+def discover(subnets: list[str], credential_groups: dict[str, dict], cache: dict) -> dict:
+    """Scans subnets and refreshes stale or missing facts in the cache."""
+    found = scan(subnets)
+    stale = {
+        host: banner
+        for host, banner in found.items()
+        if host not in cache or not is_fresh(cache[host])
+    }
+    print(f"{len(found)} SSH hosts found, {len(stale)} to identify")
+    with ThreadPoolExecutor(max_workers=8) as pool:
+        results = pool.map(lambda h: identify(h, stale[h], credential_groups), stale)
+    for host, result in zip(stale, results):
+        if result:
+            cache[host] = result
+        else:
+            print(f"[yellow]{host}: could not identify ({stale[host]})[/yellow]")
+    return cache
+
+
+def propose_entries(cache: dict) -> dict:
+    """Builds inventory entries from cached facts, keyed by hostname.
+
+    When more than one address reports the same hostname, the first address
+    is proposed and the others are reported rather than overwriting it.
+    """
+    devices = {}
+    for host, entry in sorted(cache.items()):
+        facts = entry["facts"]
+        if existing := devices.get(facts["hostname"]):
+            if existing["serial"] == facts["serial_number"]:
+                reason = "same device on another address"
+            else:
+                reason = f"different device, serial {facts['serial_number']}"
+            print(
+                f"[red]! {facts['hostname']}[/red] reported by {existing['host']} and {host} "
+                f"({reason}); keeping {existing['host']}"
+            )
+            continue
+        devices[facts["hostname"]] = {
+            "host": host,
+            "platform": entry["platform"],
+            "credential_group": entry["credential_group"],
+            "groups": [],
+            "vendor": facts["vendor"],
+            "model": facts["model"],
+            "serial": facts["serial_number"],
+            "os_version": facts["os_version"],
+        }
+    return {"devices": devices}
+
+
+def diff_inventory(proposed: dict, inventory: dict):
+    """Prints proposed entries that are new or differ from the inventory."""
+    current = inventory.get("devices") or {}
+    for name, entry in proposed["devices"].items():
+        if name not in current:
+            print(f"[green]+ {name}[/green] {entry['host']} {entry['vendor']} {entry['model']}")
+            continue
+        changed = {k: v for k, v in entry.items() if k != "groups" and current[name].get(k) != v}
+        if changed:
+            print(f"[yellow]~ {name}[/yellow] {changed}")
+
+
+def run_once():
+    cache = discover(config.SUBNETS, config.CREDENTIAL_GROUPS, load_cache(config.FACTS_CACHE))
+    with open(config.FACTS_CACHE, "w") as f:
+        json.dump(cache, f, indent=2)
+
+    proposed = propose_entries(cache)
+    inventory = {}
+    if Path(config.INVENTORY).exists():
+        with open(config.INVENTORY) as f:
+            inventory = yaml.safe_load(f) or {}
+    diff_inventory(proposed, inventory)
+    with open(config.INVENTORY_PROPOSALS, "w") as f:
+        yaml.safe_dump(proposed, f, sort_keys=False)
+
+
+if __name__ == "__main__":
+    parser = argparse.ArgumentParser(description=__doc__.splitlines()[0])
+    parser.add_argument("--interval", type=int, help="Re-run every INTERVAL seconds")
+    args = parser.parse_args()
+    while True:
+        run_once()
+        if not args.interval:
+            break
+        time.sleep(args.interval)
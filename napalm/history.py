@@ -0,0 +1,123 @@
+"""Store NAPALM traceroute results over time and detect path changes.
+
+History is kept as JSON lines, one traceroute per line:
+
+    {"timestamp": "...", "source": "...", "destination": "...", "results": {...}}
+"""
+
+import json
+from datetime import datetime, timezone
+from pathlib import Path
+
+
+def record_trace(
+    history_file: str | Path,
+    source: str,
+    destination: str,
+    results: dict,
+    timestamp: datetime | None = None,
+) -> dict:
+    """Appends a traceroute result to the history file.
+
+    Args:
+        history_file: Path to the JSON lines history file.
+        source: Source address the trace was run from.
+        destination: Destination address of the trace.
+        results: NAPALM traceroute results dict.
+        timestamp: When the trace ran. Defaults to now (UTC).
+
+    Returns:
+        The stored history entry.
+    """
+    entry = {
+        "timestamp": (timestamp or datetime.now(timezone.utc)).isoformat(),
+        "source": source,
+        "destination": destination,
+        "results": results,
+    }
+    with open(history_file, "a") as f:
+        f.write(json.dumps(entry) + "\n")
+    return entry
+
+
+def load_history(
+    history_file: str | Path, source: str | None = None, destination: str | None = None
+) -> list[dict]:
+    """Loads stored traces, oldest first, optionally filtered by source/destination."""
+    if not Path(history_file).exists():
+        return []
+    entries = []
+    with open(history_file) as f:
+        for line in f:
+            if not line.strip():
+                continue
+            entry = json.loads(line)
+            if source and entry["source"] != source:
+                continue
+            if destination and entry["destination"] != destination:
+                continue
+            entries.append(entry)
+    return sorted(entries, key=lambda e: e["timestamp"])
+
+
+def trace_path(results: dict) -> list[dict]:
+    """Reduces NAPALM traceroute results to one entry per hop.
+
+    Args:
+        results: NAPALM traceroute results dict.
+
+    Returns:
+        List of hops in order, each with the IP address and host name of the
+        first responding probe. Hops without a response have None for both.
+    """
+    if results.get("error"):
+        return []
+    path = []
+    # Keys are ints from NAPALM, but strings once round-tripped through JSON
+    for hop in sorted(results["success"], key=int):
+        probes = results["success"][hop]["probes"]
+        responding = [
+            probe for _, probe in sorted(probes.items(), key=lambda p: int(p[0]))
+            if probe.get("ip_address") and probe["ip_address"] != "*"
+        ]
+        if responding:
+            path.append(
+                {"ip_address": responding[0]["ip_address"], "host_name": responding[0]["host_name"]}
+            )
+        else:
+            path.append({"ip_address": None, "host_name": None})
+    return path
+
+
+def path_changes(history: list[dict]) -> list[dict]:
+    """Finds traces whose path differs from the previous trace of the same flow.
+
+    Args:
+        history: Entries as returned by load_history().
+
+    Returns:
+        One dict per change with source, destination, the timestamps of the
+        previous and changed traces, and the old and new paths.
+    """
+    changes = []
+    last_seen = {}
+    for entry in history:
+        flow = (entry["source"], entry["destination"])
+        path = trace_path(entry["results"])
+        if not path:
+            continue
+        previous = last_seen.get(flow)
+        ips = [hop["ip_address"] for hop in path]
+        if previous and [hop["ip_address"] for hop in previous["path"]] != ips:
+            changes.append(
+                {
+                    "source": entry["source"],
+                    "destination": entry["destination"],
+                    "previous_timestamp": previous["timestamp"],
+                    "timestamp": entry["timestamp"],
+                    "old_path": previous["path"],
+                    "new_path": path,
+                }
+            )
+        last_seen[flow] = {"timestamp": entry["timestamp"], "path": path}
+    return changes
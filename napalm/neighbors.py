@@ -0,0 +1,178 @@
+"""Routing neighbor health across the inventory.
+
+Collects BGP neighbors (NAPALM get_bgp_neighbors) and OSPF neighbors
+(parsed from "show ip ospf neighbor") from every device in the inventory's
+"routers" group and reports:
+
+- BGP neighbors that are down, or flapping (uptime below FLAP_UPTIME)
+- BGP neighbors whose received prefix count moved from the stored baseline
+- OSPF neighbors stuck in a state other than FULL (or 2WAY on DROTHER links)
+
+The report is written to config.NEIGHBOR_REPORT for the API to serve.
+
+Usage: python neighbors.py [--update-baseline]
+"""
+
+import argparse
+import json
+import re
+from concurrent.futures import ThreadPoolExecutor
+from datetime import datetime, timezone
+from pathlib import Path
+
+import config
+import yaml
+from rich import print
+from rich.table import Table
+
+from napalm import get_network_driver
+
+ROUTER_GROUP = getattr(config, "ROUTER_GROUP", "routers")
+FLAP_UPTIME = getattr(config, "FLAP_UPTIME", 15 * 60)
+# Allowed change in received prefixes before it's reported, as a fraction of the baseline
+PREFIX_TOLERANCE = getattr(config, "PREFIX_TOLERANCE", 0.1)
+OSPF_COMMANDS = {
+    "ios": "show ip ospf neighbor",
+    "eos": "show ip ospf neighbor",
+    "nxos_ssh": "show ip ospf neighbors",
+}
+
+# IOS:  10.0.0.2   1   FULL/DR         00:00:38    10.1.1.2        GigabitEthernet0/1
+# EOS:  10.0.0.2   1   default  1   FULL/DR   00:00:38   10.1.1.2   Ethernet1
+# NX-OS rows are indented:
+#  10.0.0.2          1 FULL/DR          1d02h    10.1.1.2        Eth1/1
+OSPF_PATTERN = re.compile(
+    r"^\s*(?P<neighbor_id>\d+\.\d+\.\d+\.\d+)\s+.*?(?P<state>[A-Z0-9]+(?:/ *[A-Z-]+)?)\s+"
+    r"\S+\s+(?P<address>\d+\.\d+\.\d+\.\d+)\s+(?P<interface>\S+)\s*$"
+)
+
+
+def load_routers() -> dict:
+    with open(config.INVENTORY) as f:
+        devices = (yaml.safe_load(f) or {}).get("devices") or {}
+    return {
+        name: device for name, device in devices.items() if ROUTER_GROUP in device.get("groups", [])
+    }
+
+
+def parse_ospf_neighbors(output: str) -> list[dict]:
+    neighbors = [m.groupdict() for line in output.splitlines() if (m := OSPF_PATTERN.match(line))]
+    for neighbor in neighbors:
+        neighbor["state"] = neighbor["state"].replace(" ", "")
+    return neighbors
+
+
+def collect(name: str, device: dict) -> dict:
+    """Collects BGP and OSPF neighbors from one router."""
+    driver = get_network_driver(device["platform"])
+    with driver(device["host"], config.USERNAME, config.PASSWORD) as conn:
+        bgp = conn.get_bgp_neighbors()
+        ospf = []
+        if command := OSPF_COMMANDS.get(device["platform"]):
+            ospf = parse_ospf_neighbors(conn.cli([command])[command])
+    return {"device": name, "bgp": bgp, "ospf": ospf}
+
+
+def bgp_prefixes(collected: dict) -> dict[str, int]:
+    """Received prefixes per "vrf neighbor", summed across address families."""
+    counts = {}
+    for vrf, data in collected["bgp"].items():
+        for neighbor, peer in data["peers"].items():
+            counts[f"{vrf} {neighbor}"] = sum(
+                family.get("received_prefixes", 0) or 0
+                for family in peer.get("address_family", {}).values()
+            )
+    return counts
+
+
+def check(collected: dict, baseline: dict[str, int]) -> list[dict]:
+    """Lists problems with one router's neighbors."""
+    problems = []
+    device = collected["device"]
+    prefixes = bgp_prefixes(collected)
+    for vrf, data in collected["bgp"].items():
+        for neighbor, peer in data["peers"].items():
+            key = f"{vrf} {neighbor}"
+            problem = {"device": device, "protocol": "bgp", "vrf": vrf, "neighbor": neighbor}
+            if not peer["is_enabled"]:
+                continue
+            if not peer["is_up"]:
+                problems.append({**problem, "problem": "down"})
+            elif 0 <= peer["uptime"] < FLAP_UPTIME:
+                problems.append({**problem, "problem": f"flapping (up {peer['uptime']}s)"})
+            if key in baseline:
+                expected, received = baseline[key], prefixes[key]
+                if abs(received - expected) > max(1, expected * PREFIX_TOLERANCE):
+                    problems.append({**problem, "problem": f"prefixes {expected} -> {received}"})
+    for neighbor in collected["ospf"]:
+        state = neighbor["state"].split("/")[0]
+        if state == "FULL" or neighbor["state"] == "2WAY/DROTHER":
+            continue
+        problems.append(
+            {
+                "device": device,
+                "protocol": "ospf",
+                "vrf": None,
+                "neighbor": neighbor["neighbor_id"],
+                "problem": f"stuck in {neighbor['state']} on {neighbor['interface']}",
+            }
+        )
+    return problems
+
+
+def load_baseline() -> dict:
+    if not Path(config.NEIGHBOR_BASELINE).exists():
+        return {}
+    with open(config.NEIGHBOR_BASELINE) as f:
+        return json.load(f)
+
+
+def run(update_baseline: bool = False) -> dict:
+    routers = load_routers()
+    with ThreadPoolExecutor(max_workers=16) as pool:
+        futures = {name: pool.submit(collect, name, device) for name, device in routers.items()}
+    baseline = load_baseline()
+    problems, errors = [], {}
+    for name, future in futures.items():
+        try:
+            collected = future.result()
+        except Exception as e:
+            errors[name] = str(e)
+            continue
+        problems += check(collected, baseline.get(name, {}))
+        if update_baseline:
+            baseline[name] = bgp_prefixes(collected)
+    if update_baseline:
+        with open(config.NEIGHBOR_BASELINE, "w") as f:
+            json.dump(baseline, f, indent=2)
+    report = {
+        "generated": datetime.now(timezone.utc).isoformat(),
+        "problems": problems,
+        "errors": errors,
+    }
+    with open(config.NEIGHBOR_REPORT, "w") as f:
+        json.dump(report, f, indent=2)
+    return report
+
+
+def print_report(report: dict):
+    table = Table("Device", "Protocol", "VRF", "Neighbor", "Problem", title="Routing neighbors")
+    for problem in report["problems"]:
+        table.add_row(
+            problem["device"],
+            problem["protocol"],
+            problem["vrf"] or "",
+            problem["neighbor"],
+            problem["problem"],
+        )
+    print(table)
+    for device, error in report["errors"].items():
+        print(f"[red]{device}: {error}[/red]")
+
+
+if __name__ == "__main__":
+    parser = argparse.ArgumentParser(description=__doc__.splitlines()[0])
+    parser.add_argument(
+        "--update-baseline", action="store_true", help="Store current prefix counts as baseline"
+    )
+    print_report(run(update_baseline=parser.parse_args().update_baseline))
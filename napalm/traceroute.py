@@ -1,11 +1,124 @@
+import re
+import sys
+import xml.etree.ElementTree as ET
+from pathlib import Path
+
 import config
+from history import record_trace
+from lxml import etree
+from opentelemetry import trace
 from rich import print
 
 from napalm import get_network_driver
 
-driver = get_network_driver("ios")
+# The EOS command, the Junos reply conversion and the Panorama User-ID lookups
+# are shared with the sibling netmiko/, ncclient/ and pandevice/ scripts
+REPO = Path(__file__).resolve().parent.parent
+sys.path += [str(REPO / "netmiko"), str(REPO / "ncclient"), str(REPO / "pandevice")]
+from eos_backends import check_dscp, dscp_to_tos, eos_traceroute_command  # noqa: E402
+from netconf import junos_traceroute_results, to_dict  # noqa: E402
+
+tracer = trace.get_tracer(__name__)
+
+# Linux traceroute output, as run from the EOS bash shell:
+#  2  rtr2 (10.0.0.2)  1.234 ms  1.101 ms *
+HOP_PATTERN = re.compile(r"^\s*(\d+)\s+(.*)$")
+PROBE_PATTERN = re.compile(r"(\S+) \((\S+)\)|([\d.]+) ms|(\*)")
+
+
+def parse_linux_traceroute(output: str) -> dict:
+    """Parses Linux traceroute output into the NAPALM traceroute results shape."""
+    hops = {}
+    for line in output.splitlines():
+        hop = HOP_PATTERN.match(line)
+        if not hop:
+            continue
+        probes = {}
+        host_name = ip_address = "*"
+        for name, address, rtt, timeout in PROBE_PATTERN.findall(hop.group(2)):
+            if address:
+                host_name, ip_address = name, address
+            elif rtt:
+                probes[len(probes) + 1] = {
+                    "rtt": float(rtt),
+                    "ip_address": ip_address,
+                    "host_name": host_name,
+                }
+            elif timeout:
+                probes[len(probes) + 1] = {"rtt": 0.0, "ip_address": "*", "host_name": "*"}
+        hops[int(hop.group(1))] = {"probes": probes}
+    return {"success": hops} if hops else {"error": output.strip()}
+
+
+def traceroute(
+    device, destination: str, source: str, dscp: int | None = None, payload_size: int | None = None
+) -> dict:
+    """Runs a traceroute from a NAPALM device, recording the parameters used.
+
+    NAPALM's traceroute() has no DSCP or packet size options, so when either
+    is given, EOS runs the bash traceroute through cli() and Junos runs the
+    traceroute RPC with tos and size. Other platforms don't support them.
+    """
+    if dscp is not None:
+        check_dscp(dscp)
+    attributes = {"host": device.hostname, "platform": device.platform, "destination": destination}
+    with tracer.start_as_current_span("napalm.traceroute", attributes=attributes):
+        results = _platform_traceroute(device, destination, source, dscp, payload_size)
+    results["parameters"] = {
+        "destination": destination,
+        "source": source,
+        "dscp": dscp,
+        "payload_size": payload_size,
+    }
+    return results
+
+
+def _platform_traceroute(
+    device, destination: str, source: str, dscp: int | None, payload_size: int | None
+) -> dict:
+    if dscp is None and payload_size is None:
+        return device.traceroute(destination=destination, source=source)
+    if device.platform == "eos":
+        cmd = eos_traceroute_command(destination, source, dscp=dscp, payload_size=payload_size)
+        return parse_linux_traceroute(device.cli([cmd])[cmd])
+    if device.platform == "junos":
+        options = {"host": destination, "source": source}
+        if dscp is not None:
+            options["tos"] = str(dscp_to_tos(dscp))
+        if payload_size is not None:
+            options["size"] = str(payload_size)
+        # device.device is the PyEZ connection, which returns lxml elements
+        reply = device.device.rpc.traceroute(**options)
+        return junos_traceroute_results(to_dict(ET.fromstring(etree.tostring(reply))))
+    raise NotImplementedError(
+        f"DSCP and packet size are not supported for {device.platform} traceroutes"
+    )
+
+
+if __name__ == "__main__":
+    driver = get_network_driver(getattr(config, "PLATFORM", "ios"))
+
+    with driver(config.SOURCE, config.USERNAME, config.PASSWORD) as device:
+        results = traceroute(
+            device,
+            destination=config.DEST,
+            source=config.SOURCE,
+            dscp=getattr(config, "DSCP", None),
+            payload_size=getattr(config, "PAYLOAD_SIZE", None),
+        )
+
+    if getattr(config, "HISTORY_FILE", None):
+        record_trace(config.HISTORY_FILE, config.SOURCE, config.DEST, results)
+
+    # Annotate the endpoints with their User-ID users, e.g.
+    # PANORAMA = {"hostname": ..., "api_username": ..., "api_password": ...}
+    if getattr(config, "PANORAMA", None):
+        from panos.errors import PanDeviceError
+        from subclass import Panorama
 
-with driver(config.SOURCE, config.USERNAME, config.PASSWORD) as device:
-    results = device.traceroute(destination=config.DEST, source=config.SOURCE)
+        try:
+            Panorama(**config.PANORAMA).annotate_trace(results, config.SOURCE, config.DEST)
+        except (LookupError, PanDeviceError) as e:
+            print(f"[yellow]Couldn't identify the trace endpoints: {e}[/yellow]")
 
-print(results)
+    print(results)
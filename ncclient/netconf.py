@@ -0,0 +1,218 @@
+"""NETCONF backend for Junos and IOS-XE devices.
+
+Fetches running config, interface state and routes as dicts, and runs
+traceroute/ping RPCs where the platform has them (Junos). Traceroute and ping
+results have the same shape as NAPALM's traceroute() and ping(), so they can
+go wherever NAPALM results do (PTR resolution, history, export):
+
+    with NetconfBackend(host, username, password, platform="junos") as device:
+        device.get_interfaces()
+        device.traceroute("8.8.8.8", source="10.1.1.1")
+
+Run netconf_stub.py and use port=8830 to try it locally.
+"""
+
+import sys
+import xml.etree.ElementTree as ET
+from pathlib import Path
+
+import config
+from rich import print
+
+from ncclient import manager
+from ncclient.xml_ import to_ele
+
+# The DSCP range check and TOS conversion are shared with netmiko/eos_backends.py
+sys.path.append(str(Path(__file__).resolve().parent.parent / "netmiko"))
+from eos_backends import dscp_to_tos  # noqa: E402
+
+IETF_INTERFACES = """
+<interfaces-state xmlns="urn:ietf:params:xml:ns:yang:ietf-interfaces"/>
+"""
+IETF_ROUTING = """
+<routing-state xmlns="urn:ietf:params:xml:ns:yang:ietf-routing"/>
+"""
+
+
+def to_dict(element: ET.Element) -> dict | str:
+    """Converts XML to nested dicts, dropping namespaces.
+
+    Repeated child elements become lists. Leaf elements become their text.
+    """
+    children = list(element)
+    if not children:
+        return (element.text or "").strip()
+    result = {}
+    for child in children:
+        tag = child.tag.split("}")[-1]
+        value = to_dict(child)
+        if tag in result:
+            if not isinstance(result[tag], list):
+                result[tag] = [result[tag]]
+            result[tag].append(value)
+        else:
+            result[tag] = value
+    return result
+
+
+def _usec_to_ms(value: str | None) -> float:
+    # Junos reports RTTs in microseconds, NAPALM in milliseconds
+    return float(value) / 1000 if value else 0.0
+
+
+def _as_list(value) -> list:
+    if value in (None, ""):
+        return []
+    return value if isinstance(value, list) else [value]
+
+
+def junos_traceroute_results(reply: dict) -> dict:
+    """Converts a Junos traceroute RPC reply to the NAPALM traceroute results shape."""
+    results = reply.get("traceroute-results", reply)
+    if not isinstance(results, dict) or "hop" not in results:
+        failure = results.get("traceroute-failure") if isinstance(results, dict) else None
+        return {"error": str(failure or results)}
+    hops = {}
+    for hop in _as_list(results["hop"]):
+        probes = {}
+        for index, probe in enumerate(_as_list(hop.get("probe-result")), start=1):
+            address = probe.get("ip-address") or "*"
+            probes[int(probe.get("probe-index") or index)] = {
+                "rtt": _usec_to_ms(probe.get("rtt")),
+                "ip_address": address,
+                "host_name": probe.get("host-name") or address,
+            }
+        hops[int(hop["ttl-value"])] = {"probes": probes}
+    return {"success": hops}
+
+
+def junos_ping_results(reply: dict) -> dict:
+    """Converts a Junos ping RPC reply to the NAPALM ping results shape."""
+    results = reply.get("ping-results", reply)
+    summary = results.get("probe-results-summary") if isinstance(results, dict) else None
+    if not summary:
+        failure = results.get("ping-failure") if isinstance(results, dict) else None
+        return {"error": str(failure or results)}
+    return {
+        "success": {
+            "probes_sent": int(summary.get("probes-sent") or 0),
+            "packet_loss": int(summary.get("packet-loss") or 0),
+            "rtt_min": _usec_to_ms(summary.get("rtt-minimum")),
+            "rtt_max": _usec_to_ms(summary.get("rtt-maximum")),
+            "rtt_avg": _usec_to_ms(summary.get("rtt-average")),
+            "rtt_stddev": _usec_to_ms(summary.get("rtt-stddev")),
+            "results": [
+                {"ip_address": probe.get("ip-address"), "rtt": _usec_to_ms(probe.get("rtt"))}
+                for probe in _as_list(results.get("probe-result"))
+            ],
+        }
+    }
+
+
+class NetconfBackend:
+    def __init__(
+        self, host: str, username: str, password: str, platform: str = "junos", port: int = 830
+    ):
+        if platform not in ("junos", "iosxe"):
+            raise ValueError(f"Unsupported platform {platform}")
+        self.platform = platform
+        self.conn = manager.connect(
+            host=host,
+            port=port,
+            username=username,
+            password=password,
+            device_params={"name": platform},
+            hostkey_verify=False,
+            look_for_keys=False,
+            allow_agent=False,
+        )
+
+    def __enter__(self):
+        return self
+
+    def __exit__(self, *exc):
+        self.close()
+
+    def close(self):
+        self.conn.close_session()
+
+    def _parse(self, reply) -> dict:
+        return to_dict(ET.fromstring(reply.xml.encode()))
+
+    def rpc(self, rpc: str | ET.Element) -> dict:
+        """Runs an arbitrary RPC, given as XML, and returns the reply as a dict."""
+        if isinstance(rpc, ET.Element):
+            rpc = ET.tostring(rpc, encoding="unicode")
+        return self._parse(self.conn.dispatch(to_ele(rpc)))
+
+    def get_config(self, source: str = "running") -> dict:
+        return self._parse(self.conn.get_config(source=source))["data"]
+
+    def get_interfaces(self) -> dict:
+        if self.platform == "junos":
+            return self.rpc("<get-interface-information><terse/></get-interface-information>")
+        return self._parse(self.conn.get(filter=("subtree", IETF_INTERFACES)))["data"]
+
+    def get_routes(self) -> dict:
+        if self.platform == "junos":
+            return self.rpc("<get-route-information/>")
+        return self._parse(self.conn.get(filter=("subtree", IETF_ROUTING)))["data"]
+
+    def _junos_only(self, operation: str):
+        if self.platform != "junos":
+            raise NotImplementedError(f"{operation} RPC is not available on {self.platform}")
+
+    def traceroute(
+        self,
+        destination: str,
+        source: str | None = None,
+        ttl: int = 30,
+        dscp: int | None = None,
+        payload_size: int | None = None,
+    ) -> dict:
+        """Runs the Junos traceroute RPC, returning NAPALM traceroute results.
+
+        The DSCP and payload size used are recorded under "parameters".
+        """
+        self._junos_only("traceroute")
+        rpc = ET.Element("traceroute")
+        ET.SubElement(rpc, "host").text = destination
+        ET.SubElement(rpc, "ttl").text = str(ttl)
+        if source:
+            ET.SubElement(rpc, "source").text = source
+        if dscp is not None:
+            # Junos takes the whole TOS byte
+            ET.SubElement(rpc, "tos").text = str(dscp_to_tos(dscp))
+        if payload_size is not None:
+            ET.SubElement(rpc, "size").text = str(payload_size)
+        results = junos_traceroute_results(self.rpc(rpc))
+        results["parameters"] = {
+            "destination": destination,
+            "source": source,
+            "ttl": ttl,
+            "dscp": dscp,
+            "payload_size": payload_size,
+        }
+        return results
+
+    def ping(self, destination: str, source: str | None = None, count: int = 5) -> dict:
+        """Runs the Junos ping RPC, returning NAPALM ping results."""
+        self._junos_only("ping")
+        rpc = ET.Element("ping")
+        ET.SubElement(rpc, "host").text = destination
+        ET.SubElement(rpc, "count").text = str(count)
+        if source:
+            ET.SubElement(rpc, "source").text = source
+        return junos_ping_results(self.rpc(rpc))
+
+
+if __name__ == "__main__":
+    with NetconfBackend(
+        config.SOURCE,
+        config.USERNAME,
+        config.PASSWORD,
+        platform=getattr(config, "PLATFORM", "junos"),
+        port=getattr(config, "NETCONF_PORT", 830),
+    ) as device:
+        print(device.get_interfaces())
+        print(device.traceroute(config.DEST, source=config.SOURCE))
@@ -0,0 +1,133 @@
+""" Local stand-in NETCONF server (SSH subsystem, base:1.0 framing), to test netconf.py.
+
+Accepts any username/password on port 8830 and answers get-config, get and a
+few Junos RPCs with canned data. Set NETCONF_PORT = 8830 in config.py, run
+this, then netconf.py.
+"""
+
+import socket
+import threading
+import xml.etree.ElementTree as ET
+
+import paramiko
+
+EOM = "]]>]]>"
+NS = "urn:ietf:params:xml:ns:netconf:base:1.0"
+HELLO = f"""<?xml version="1.0" encoding="UTF-8"?>
+<hello xmlns="{NS}">
+  <capabilities><capability>urn:ietf:params:netconf:base:1.0</capability></capabilities>
+  <session-id>1</session-id>
+</hello>"""
+REPLIES = {
+    "get-config": """<data>
+  <configuration><system><host-name>stub</host-name></system></configuration>
+</data>""",
+    "get": """<data>
+  <interfaces-state xmlns="urn:ietf:params:xml:ns:yang:ietf-interfaces">
+    <interface><name>GigabitEthernet1</name><oper-status>up</oper-status></interface>
+  </interfaces-state>
+</data>""",
+    "get-interface-information": """<interface-information>
+  <physical-interface><name>ge-0/0/0</name><oper-status>up</oper-status></physical-interface>
+  <physical-interface><name>ge-0/0/1</name><oper-status>down</oper-status></physical-interface>
+</interface-information>""",
+    "get-route-information": """<route-information><route-table>
+  <table-name>inet.0</table-name>
+  <rt><rt-destination>0.0.0.0/0</rt-destination></rt>
+</route-table></route-information>""",
+    "traceroute": """<traceroute-results>
+  <target-host>8.8.8.8</target-host>
+  <hop><ttl-value>1</ttl-value>
+    <probe-result><probe-index>1</probe-index><ip-address>10.0.0.1</ip-address>
+      <rtt>512</rtt></probe-result>
+    <probe-result><probe-index>2</probe-index><probe-failure/></probe-result></hop>
+  <hop><ttl-value>2</ttl-value>
+    <probe-result><probe-index>1</probe-index><ip-address>8.8.8.8</ip-address>
+      <host-name>dns.google</host-name><rtt>9871</rtt></probe-result></hop>
+</traceroute-results>""",
+    "ping": """<ping-results>
+  <target-host>8.8.8.8</target-host>
+  <probe-result><ip-address>8.8.8.8</ip-address><rtt>9640</rtt></probe-result>
+  <probe-results-summary><probes-sent>5</probes-sent><responses-received>5</responses-received>
+    <packet-loss>0</packet-loss><rtt-minimum>9502</rtt-minimum><rtt-maximum>9871</rtt-maximum>
+    <rtt-average>9640</rtt-average><rtt-stddev>120</rtt-stddev></probe-results-summary>
+</ping-results>""",
+}
+
+
+class Server(paramiko.ServerInterface):
+    def __init__(self):
+        self.subsystem = threading.Event()
+
+    def check_channel_request(self, kind, chanid):
+        if kind == "session":
+            return paramiko.OPEN_SUCCEEDED
+        return paramiko.OPEN_FAILED_ADMINISTRATIVELY_PROHIBITED
+
+    def get_allowed_auths(self, username):
+        return "password"
+
+    def check_auth_password(self, username, password):
+        return paramiko.AUTH_SUCCESSFUL
+
+    def check_channel_subsystem_request(self, channel, name):
+        if name == "netconf":
+            self.subsystem.set()
+            return True
+        return False
+
+
+def reply(message: str) -> tuple[str | None, bool]:
+    """Builds the rpc-reply for a request.
+
+    Returns:
+        The reply (None for the client's hello) and whether to end the session.
+    """
+    rpc = ET.fromstring(message)
+    if rpc.tag.split("}")[-1] == "hello":
+        return None, False
+    message_id = rpc.get("message-id", "")
+    operation = list(rpc)[0].tag.split("}")[-1]
+    body = REPLIES.get(operation, "<ok/>")
+    response = f'<rpc-reply xmlns="{NS}" message-id="{message_id}">{body}</rpc-reply>'
+    return response, operation == "close-session"
+
+
+def handle(client: socket.socket, host_key: paramiko.RSAKey):
+    transport = paramiko.Transport(client)
+    transport.add_server_key(host_key)
+    server = Server()
+    transport.start_server(server=server)
+    channel = transport.accept(timeout=10)
+    if channel is None or not server.subsystem.wait(10):
+        transport.close()
+        return
+    channel.sendall(HELLO + EOM)
+    buffer = ""
+    try:
+        while True:
+            data = channel.recv(65535)
+            if not data:
+                break
+            buffer += data.decode()
+            while EOM in buffer:
+                message, buffer = buffer.split(EOM, 1)
+                response, done = reply(message.strip())
+                if response:
+                    channel.sendall(response + EOM)
+                if done:
+                    return
+    finally:
+        channel.close()
+        transport.close()
+
+
+if __name__ == "__main__":
+    host_key = paramiko.RSAKey.generate(2048)
+    sock = socket.socket(socket.AF_INET, socket.SOCK_STREAM)
+    sock.setsockopt(socket.SOL_SOCKET, socket.SO_REUSEADDR, 1)
+    sock.bind(("127.0.0.1", 8830))
+    sock.listen()
+    while True:
+        client, _ = sock.accept()
+        threading.Thread(target=handle, args=(client, host_key), daemon=True).start()
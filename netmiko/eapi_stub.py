@@ -0,0 +1,70 @@
+""" Local stand-in for an EOS eAPI endpoint, to test EapiBackend.
+
+Set EOS_TRANSPORT = "http" and EOS_PORT = 8080 in config.py, run this, then eos_cmd.py.
+"""
+
+import json
+from http.server import BaseHTTPRequestHandler, HTTPServer
+
+JSON_RESPONSES = {
+    "show version": {
+        "modelName": "vEOS-lab",
+        "version": "4.30.1F",
+        "serialNumber": "STUB0001",
+        "hostname": "eos-stub",
+    },
+    "show hostname": {"hostname": "eos-stub", "fqdn": "eos-stub.example.com"},
+}
+TEXT_RESPONSES = {
+    "bash": (
+        "traceroute to 8.8.8.8 (8.8.8.8), 30 hops max, 60 byte packets\n"
+        " 1  10.0.0.1 (10.0.0.1)  0.512 ms  0.431 ms  0.402 ms\n"
+        " 2  8.8.8.8 (8.8.8.8)  9.871 ms  9.802 ms  9.755 ms\n"
+    ),
+    "ping": (
+        "PING 8.8.8.8 (8.8.8.8) 72(100) bytes of data.\n"
+        "80 bytes from 8.8.8.8: icmp_seq=1 ttl=118 time=9.80 ms\n"
+    ),
+}
+
+
+class EapiHandler(BaseHTTPRequestHandler):
+    def do_POST(self):
+        if self.path != "/command-api":
+            self.send_error(404)
+            return
+        request = json.loads(self.rfile.read(int(self.headers["Content-Length"])))
+        params = request["params"]
+        results = []
+        for cmd in params["cmds"]:
+            if params.get("format", "json") == "text":
+                output = TEXT_RESPONSES.get(cmd.split()[0])
+                result = {"output": output} if output is not None else None
+            else:
+                result = JSON_RESPONSES.get(cmd)
+            if result is None:
+                body = {
+                    "jsonrpc": "2.0",
+                    "id": request["id"],
+                    "error": {
+                        "code": 1002,
+                        "message": f"CLI command {len(results) + 1} of {len(params['cmds'])} "
+                        f"'{cmd}' failed: invalid command",
+                        "data": results + [{"errors": ["Invalid input"]}],
+                    },
+                }
+                break
+            results.append(result)
+        else:
+            body = {"jsonrpc": "2.0", "id": request["id"], "result": results}
+
+        payload = json.dumps(body).encode()
+        self.send_response(200)
+        self.send_header("Content-Type", "application/json")
+        self.send_header("Content-Length", str(len(payload)))
+        self.end_headers()
+        self.wfile.write(payload)
+
+
+if __name__ == "__main__":
+    HTTPServer(("127.0.0.1", 8080), EapiHandler).serve_forever()
@@ -0,0 +1,214 @@
+"""EOS command backends: SSH (Netmiko) and eAPI (JSON-RPC over HTTPS).
+
+Both expose the same interface, so callers pick a transport and keep the
+rest of their code:
+
+    with get_backend("eapi", host, username, password) as eos:
+        eos.show("show version")
+        eos.traceroute("8.8.8.8", source="10.1.1.1")
+
+Run eapi_stub.py and use transport="http", port=8080 to try eAPI locally.
+"""
+
+import itertools
+import json
+from abc import ABC, abstractmethod
+
+import httpx
+from opentelemetry import trace
+
+from netmiko import ConnectHandler
+
+tracer = trace.get_tracer(__name__)
+
+
+class EapiError(Exception):
+    def __init__(self, code: int, message: str, data=None):
+        super().__init__(f"eAPI error {code}: {message}")
+        self.code = code
+        self.data = data
+
+
+def check_dscp(dscp: int):
+    """Raises ValueError unless dscp is a valid DSCP value, 0-63."""
+    if not 0 <= dscp <= 63:
+        raise ValueError(f"DSCP must be 0-63, got {dscp}")
+
+
+def dscp_to_tos(dscp: int) -> int:
+    """The TOS / traffic class byte for a DSCP value, which takes its upper 6 bits."""
+    check_dscp(dscp)
+    return dscp << 2
+
+
+def eos_traceroute_command(
+    destination: str,
+    source: str | None = None,
+    max_hops: int = 30,
+    wait: int = 2,
+    dscp: int | None = None,
+    payload_size: int | None = None,
+) -> str:
+    """Builds a bash traceroute command for EOS.
+
+    Linux traceroute takes the TOS byte (DSCP shifted left by 2) and the total
+    packet length, i.e. the payload plus 28 bytes of IP and UDP headers.
+    """
+    cmd = f"bash timeout 60 traceroute -m {max_hops} -w {wait}"
+    if source:
+        cmd += f" -s {source}"
+    if dscp is not None:
+        cmd += f" -t {dscp_to_tos(dscp)}"
+    cmd += f" {destination}"
+    if payload_size is not None:
+        cmd += f" {payload_size + 28}"
+    return cmd
+
+
+class EosBackend(ABC):
+    @abstractmethod
+    def run_commands(self, commands: list[str], format: str = "json") -> list:
+        """Runs commands, returning one result per command.
+
+        Results are dicts for format="json" and strings for format="text".
+        """
+
+    def close(self):
+        pass
+
+    def __enter__(self):
+        return self
+
+    def __exit__(self, *exc):
+        self.close()
+
+    def show(self, command: str) -> dict:
+        return self.run_commands([command])[0]
+
+    def traceroute(
+        self,
+        destination: str,
+        source: str | None = None,
+        max_hops: int = 30,
+        wait: int = 2,
+        dscp: int | None = None,
+        payload_size: int | None = None,
+    ) -> dict:
+        """Runs traceroute from the EOS bash shell.
+
+        Args:
+            destination: Address to trace to.
+            source: Source address.
+            max_hops: Maximum TTL.
+            wait: Seconds to wait for each probe.
+            dscp: DSCP value to mark probes with.
+            payload_size: Probe payload in bytes, excluding IP and UDP headers.
+
+        Returns:
+            Dict with the traceroute output and the parameters used.
+        """
+        cmd = eos_traceroute_command(destination, source, max_hops, wait, dscp, payload_size)
+        return {
+            "output": self.run_commands([cmd], format="text")[0],
+            "parameters": {
+                "destination": destination,
+                "source": source,
+                "max_hops": max_hops,
+                "wait": wait,
+                "dscp": dscp,
+                "payload_size": payload_size,
+            },
+        }
+
+    def ping(self, destination: str, source: str | None = None, count: int = 5) -> str:
+        cmd = f"ping {destination} repeat {count}"
+        if source:
+            cmd += f" source {source}"
+        return self.run_commands([cmd], format="text")[0]
+
+
+class SshBackend(EosBackend):
+    def __init__(self, host: str, username: str, password: str, **kwargs):
+        self.host = host
+        with tracer.start_as_current_span("netmiko.connect", attributes={"host": host}):
+            self.conn = ConnectHandler(
+                host=host, username=username, password=password, device_type="arista_eos", **kwargs
+            )
+
+    def send_command(self, command: str) -> str:
+        with tracer.start_as_current_span(
+            "netmiko.send_command", attributes={"host": self.host, "command": command}
+        ):
+            return self.conn.send_command(command)
+
+    def run_commands(self, commands: list[str], format: str = "json") -> list:
+        if format == "json":
+            return [json.loads(self.send_command(f"{cmd} | json")) for cmd in commands]
+        return [self.send_command(cmd) for cmd in commands]
+
+    def close(self):
+        self.conn.disconnect()
+
+
+# eAPI transports and the URL scheme they use
+EAPI_SCHEMES = {"eapi": "https", "https": "https", "http": "http"}
+
+
+class EapiBackend(EosBackend):
+    def __init__(
+        self,
+        host: str,
+        username: str,
+        password: str,
+        transport: str = "https",
+        port: int | None = None,
+        verify: bool = False,
+        timeout: float = 90,
+    ):
+        port = port or (443 if transport == "https" else 80)
+        self.client = httpx.Client(
+            base_url=f"{transport}://{host}:{port}",
+            auth=(username, password),
+            verify=verify,
+            timeout=timeout,
+        )
+        self.ids = itertools.count(1)
+
+    def run_commands(self, commands: list[str], format: str = "json") -> list:
+        request_id = next(self.ids)
+        resp = self.client.post(
+            "/command-api",
+            json={
+                "jsonrpc": "2.0",
+                "method": "runCmds",
+                "params": {"version": 1, "cmds": commands, "format": format},
+                "id": request_id,
+            },
+        )
+        resp.raise_for_status()
+        body = resp.json()
+        if "error" in body:
+            error = body["error"]
+            raise EapiError(error["code"], error["message"], error.get("data"))
+        if format == "text":
+            return [result["output"] for result in body["result"]]
+        return body["result"]
+
+    def close(self):
+        self.client.close()
+
+
+def get_backend(transport: str, host: str, username: str, password: str, **kwargs) -> EosBackend:
+    """Returns an SSH backend for transport="ssh", or an eAPI backend.
+
+    eAPI runs over "https" (or "eapi", its alias) or plain "http".
+
+    Raises:
+        ValueError: The transport isn't ssh, eapi, https or http.
+    """
+    if transport == "ssh":
+        return SshBackend(host, username, password, **kwargs)
+    scheme = EAPI_SCHEMES.get(transport)
+    if not scheme:
+        raise ValueError(f"Unknown EOS transport {transport!r}, expected ssh, eapi, https or http")
+    return EapiBackend(host, username, password, transport=scheme, **kwargs)
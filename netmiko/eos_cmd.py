@@ -1,10 +1,19 @@
 import config
+from eos_backends import get_backend
+from rich import print
 
-from netmiko import ConnectHandler
-
-CMD = f"bash timeout 60 traceroute {config.DEST} -s {config.SOURCE} -m 30 -w 2"
-
-with ConnectHandler(
-    host=config.SOURCE, username=config.USERNAME, password=config.PASSWORD, device_type="arista_eos"
+with get_backend(
+    getattr(config, "EOS_TRANSPORT", "ssh"),
+    host=config.SOURCE,
+    username=config.USERNAME,
+    password=config.PASSWORD,
+    port=getattr(config, "EOS_PORT", None),
 ) as conn:
-    results = conn.send_command(CMD)
+    results = conn.traceroute(
+        config.DEST,
+        source=config.SOURCE,
+        dscp=getattr(config, "DSCP", None),
+        payload_size=getattr(config, "PAYLOAD_SIZE", None),
+    )
+
+print(results)
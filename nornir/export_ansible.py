@@ -0,0 +1,92 @@
+"""Export the device inventory as an Ansible inventory (YAML or INI).
+
+Devices become hosts in one group per inventory group (plus one per
+platform), with connection settings in group vars. Passwords aren't
+exported; Ansible reads them from the NET_PASSWORD environment variable.
+
+Usage: python export_ansible.py [--format yaml|ini] > hosts.yaml
+"""
+
+import argparse
+import re
+
+import config
+import yaml
+
+NETWORK_OS = {
+    "ios": "cisco.ios.ios",
+    "eos": "arista.eos.eos",
+    "junos": "junipernetworks.junos.junos",
+    "nxos_ssh": "cisco.nxos.nxos",
+    "panos": "paloaltonetworks.panos.panos",
+}
+# pan-os modules connect through their own provider argument, not network_cli
+CONNECTION = {"panos": "local"}
+CREDENTIAL_VARS = {
+    "ansible_user": config.USERNAME,
+    "ansible_password": "{{ lookup('env', 'NET_PASSWORD') }}",
+}
+
+
+def load_inventory() -> dict:
+    with open(config.INVENTORY) as f:
+        return (yaml.safe_load(f) or {}).get("devices") or {}
+
+
+def group_name(name: str) -> str:
+    """Ansible group names may only contain letters, digits and underscores."""
+    return re.sub(r"\W", "_", name)
+
+
+def ansible_inventory(devices: dict) -> dict:
+    groups: dict[str, dict] = {}
+    for name, device in devices.items():
+        host_vars = {"ansible_host": device["host"]}
+        for key in ("vendor", "model", "serial", "os_version"):
+            if device.get(key):
+                host_vars[key] = device[key]
+        for group in [f"platform_{device['platform']}"] + device.get("groups", []):
+            groups.setdefault(group_name(group), {"hosts": {}})["hosts"][name] = dict(host_vars)
+
+    group_vars = getattr(config, "GROUP_VARS", {})
+    for group, data in groups.items():
+        platform = group.removeprefix("platform_")
+        if group.startswith("platform_") and platform in NETWORK_OS:
+            data["vars"] = {
+                "ansible_network_os": NETWORK_OS[platform],
+                "ansible_connection": CONNECTION.get(platform, "ansible.netcommon.network_cli"),
+                **CREDENTIAL_VARS,
+            }
+        if group in group_vars:
+            data.setdefault("vars", {}).update(group_vars[group])
+    return {"all": {"children": dict(sorted(groups.items()))}}
+
+
+def ini_var(key: str, value) -> str:
+    """key=value, quoted if the value has spaces (e.g. an os_version)."""
+    return f'{key}="{value}"' if " " in str(value) else f"{key}={value}"
+
+
+def to_ini(inventory: dict) -> str:
+    lines = []
+    for group, data in inventory["all"]["children"].items():
+        lines.append(f"[{group}]")
+        for host, host_vars in data["hosts"].items():
+            lines.append(" ".join([host] + [ini_var(k, v) for k, v in host_vars.items()]))
+        lines.append("")
+        if data.get("vars"):
+            lines.append(f"[{group}:vars]")
+            for key, value in data["vars"].items():
+                lines.append(ini_var(key, value))
+            lines.append("")
+    return "\n".join(lines)
+
+
+if __name__ == "__main__":
+    parser = argparse.ArgumentParser(description=__doc__.splitlines()[0])
+    parser.add_argument("--format", choices=["yaml", "ini"], default="yaml")
+    inventory = ansible_inventory(load_inventory())
+    if parser.parse_args().format == "ini":
+        print(to_ini(inventory))
+    else:
+        print(yaml.safe_dump(inventory, sort_keys=False))
@@ -0,0 +1,69 @@
+"""Nornir inventory plugin for the device inventory file.
+
+Hosts keep their inventory groups and facts. Credentials are shared from
+config.py: USERNAME/PASSWORD through the inventory defaults, or the host's
+credential_group in CREDENTIAL_GROUPS (as used by napalm/discovery.py):
+
+    nr = InitNornir(inventory={"plugin": "SandboxInventory"})
+"""
+
+import config
+import yaml
+from nornir.core.inventory import (
+    ConnectionOptions,
+    Defaults,
+    Group,
+    Groups,
+    Host,
+    Hosts,
+    Inventory,
+    ParentGroups,
+)
+from nornir.core.plugins.inventory import InventoryPluginRegister
+
+# NAPALM drivers whose name differs from the Netmiko device type
+NETMIKO_PLATFORMS = {
+    "ios": "cisco_ios",
+    "eos": "arista_eos",
+    "junos": "juniper_junos",
+    "nxos_ssh": "cisco_nxos",
+    "panos": "paloalto_panos",
+}
+
+
+class SandboxInventory:
+    def __init__(self, inventory_file: str | None = None):
+        self.inventory_file = inventory_file or config.INVENTORY
+
+    def load(self) -> Inventory:
+        with open(self.inventory_file) as f:
+            devices = (yaml.safe_load(f) or {}).get("devices") or {}
+
+        defaults = Defaults(username=config.USERNAME, password=config.PASSWORD)
+        groups = Groups()
+        for device in devices.values():
+            for name in device.get("groups", []):
+                groups.setdefault(name, Group(name=name, defaults=defaults))
+
+        credential_groups = getattr(config, "CREDENTIAL_GROUPS", {})
+        hosts = Hosts()
+        for name, device in devices.items():
+            platform = device["platform"]
+            credentials = credential_groups.get(device.get("credential_group"), {})
+            hosts[name] = Host(
+                name=name,
+                hostname=device["host"],
+                username=credentials.get("username"),
+                password=credentials.get("password"),
+                platform=platform,
+                groups=ParentGroups(groups[group] for group in device.get("groups", [])),
+                data={k: v for k, v in device.items() if k not in ("host", "platform", "groups")},
+                connection_options={
+                    "netmiko": ConnectionOptions(platform=NETMIKO_PLATFORMS.get(platform, platform))
+                },
+                defaults=defaults,
+            )
+        return Inventory(hosts=hosts, groups=groups, defaults=defaults)
+
+
+InventoryPluginRegister.register("SandboxInventory", SandboxInventory)
@@ -0,0 +1,88 @@
+"""Traceroute, policy-match and PTR-enrichment operations as Nornir tasks.
+
+Usage: python tasks.py DESTINATION [--group routers] [--dscp 46] [--payload-size 1400]
+"""
+
+import argparse
+import sys
+import xml.etree.ElementTree as ET
+from pathlib import Path
+
+import config
+import inventory_plugin  # noqa: F401 registers SandboxInventory
+from nornir import InitNornir
+from nornir.core.task import Result, Task
+from nornir_utils.plugins.functions import print_result
+
+# Traceroutes, PTR lookups and policy matches are shared with the sibling
+# napalm/, dns/ and pandevice/ scripts
+REPO = Path(__file__).resolve().parent.parent
+sys.path += [str(REPO / "napalm"), str(REPO / "dns"), str(REPO / "pandevice")]
+import traceroute as napalm_traceroute  # noqa: E402
+from resolve_ptr import resolve_traceroute_ptrs  # noqa: E402
+from subclass import Panorama  # noqa: E402
+
+
+def traceroute(
+    task: Task,
+    destination: str,
+    source: str = "",
+    dscp: int | None = None,
+    payload_size: int | None = None,
+) -> Result:
+    """NAPALM traceroute from the host, with napalm/traceroute.py's options and parameters."""
+    device = task.host.get_connection("napalm", task.nornir.config)
+    results = napalm_traceroute.traceroute(
+        device, destination, source, dscp=dscp, payload_size=payload_size
+    )
+    return Result(host=task.host, result=results)
+
+
+def resolve_ptrs(task: Task, traceroute_results: dict) -> Result:
+    """Resolves hop addresses in NAPALM traceroute results to PTR names, with dns/resolve_ptr.py."""
+    return Result(host=task.host, result=resolve_traceroute_ptrs(traceroute_results))
+
+
+def traceroute_with_ptrs(task: Task, destination: str, source: str = "", **options) -> Result:
+    results = task.run(task=traceroute, destination=destination, source=source, **options).result
+    enriched = task.run(task=resolve_ptrs, traceroute_results=results).result
+    return Result(host=task.host, result=enriched)
+
+
+def policy_match(
+    task: Task, source: str, destination: str, protocol: int, port: int, user: str | None = None
+) -> Result:
+    """Runs "test security-policy-match" on a firewall host, through Panorama.
+
+    The firewall is addressed by its inventory serial, and Panorama by
+    config.PANORAMA = {"hostname": ..., "api_username": ..., "api_password": ...}.
+    """
+    result = Panorama(**config.PANORAMA).test_security_policy_match(
+        source=source,
+        destination=destination,
+        protocol=protocol,
+        port=port,
+        user=user,
+        serial=task.host["serial"],
+    )
+    return Result(host=task.host, result=ET.tostring(result, encoding="unicode"))
+
+
+if __name__ == "__main__":
+    parser = argparse.ArgumentParser(description=__doc__.splitlines()[0])
+    parser.add_argument("destination")
+    parser.add_argument("--group", default="routers")
+    parser.add_argument("--dscp", type=int)
+    parser.add_argument("--payload-size", type=int)
+    args = parser.parse_args()
+
+    nr = InitNornir(inventory={"plugin": "SandboxInventory"})
+    routers = nr.filter(filter_func=lambda host: args.group in host.groups)
+    print_result(
+        routers.run(
+            task=traceroute_with_ptrs,
+            destination=args.destination,
+            dscp=args.dscp,
+            payload_size=args.payload_size,
+        )
+    )
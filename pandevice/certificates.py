@@ -0,0 +1,150 @@
+"""Certificate expiry report for Panorama and its managed firewalls.
+
+Lists every certificate in the running config of Panorama and each connected
+firewall with its subject, issuer, usage (management, GlobalProtect,
+decryption) and days to expiry. Certificates within CERT_WARN_DAYS or
+CERT_CRIT_DAYS of expiry are posted to config.ALERT_WEBHOOK, if set.
+"""
+
+import sys
+import xml.etree.ElementTree as ET
+from datetime import datetime, timezone
+
+import config
+import httpx
+from rich import print
+from rich.table import Table
+from subclass import Panorama
+
+WARN_DAYS = getattr(config, "CERT_WARN_DAYS", 30)
+CRIT_DAYS = getattr(config, "CERT_CRIT_DAYS", 7)
+
+
+def running_config(pano: Panorama, serial: str | None = None) -> ET.Element:
+    """Running config of Panorama, or of a managed firewall through Panorama."""
+    pano.xapi.show(xpath="/config", extra_qs={"target": serial} if serial else None)
+    return pano.xapi.element_root
+
+
+def profile_certificates(root: ET.Element) -> dict[str, str]:
+    """SSL/TLS service profile name to certificate name."""
+    return {
+        entry.get("name"): entry.findtext("certificate")
+        for profiles in root.iter("ssl-tls-service-profile")
+        for entry in profiles.findall("entry")
+        if entry.findtext("certificate")
+    }
+
+
+def certificate_usage(root: ET.Element) -> dict[str, set[str]]:
+    """Certificate name to the features that use it."""
+    profiles = profile_certificates(root)
+    usage: dict[str, set[str]] = {}
+
+    def use(certificate: str | None, feature: str):
+        if certificate:
+            usage.setdefault(certificate, set()).add(feature)
+
+    for system in root.iter("system"):
+        use(profiles.get(system.findtext("ssl-tls-service-profile")), "management")
+    for portal in root.iter("global-protect-portal"):
+        for entry in portal.findall("entry"):
+            profile = entry.findtext("portal-config/ssl-tls-service-profile")
+            use(profiles.get(profile), "globalprotect-portal")
+    for gateway in root.iter("global-protect-gateway"):
+        for entry in gateway.findall("entry"):
+            use(profiles.get(entry.findtext("ssl-tls-service-profile")), "globalprotect-gateway")
+    for decrypt in root.iter("ssl-decrypt"):
+        for tag in ("forward-trust-certificate", "forward-untrust-certificate"):
+            for element in decrypt.findall(tag):
+                use(element.findtext("rsa"), "decryption")
+                use(element.findtext("ecdsa"), "decryption")
+        for member in decrypt.findall("trusted-root-CA/member"):
+            use(member.text, "decryption-trusted-root")
+    return usage
+
+
+def expires(entry: ET.Element) -> datetime | None:
+    if epoch := entry.findtext("expiry-epoch"):
+        return datetime.fromtimestamp(int(epoch), timezone.utc)
+    if not_after := entry.findtext("not-valid-after"):
+        # e.g. "Jan  1 00:00:00 2030 GMT"
+        return datetime.strptime(" ".join(not_after.split()), "%b %d %H:%M:%S %Y %Z").replace(
+            tzinfo=timezone.utc
+        )
+    return None
+
+
+def certificates(root: ET.Element, device: str) -> list[dict]:
+    usage = certificate_usage(root)
+    now = datetime.now(timezone.utc)
+    certs = []
+    for container in root.iter("certificate"):
+        for entry in container.findall("entry"):
+            # Skip certificate references, e.g. in SSL/TLS service profiles
+            if entry.find("subject") is None and entry.find("common-name") is None:
+                continue
+            expiry = expires(entry)
+            certs.append(
+                {
+                    "device": device,
+                    "name": entry.get("name"),
+                    "subject": entry.findtext("subject") or entry.findtext("common-name"),
+                    "issuer": entry.findtext("issuer"),
+                    "usage": sorted(usage.get(entry.get("name"), [])),
+                    "expires": expiry.isoformat() if expiry else None,
+                    "days": (expiry - now).days if expiry else None,
+                }
+            )
+    return certs
+
+
+def send_alert(message: str):
+    webhook = getattr(config, "ALERT_WEBHOOK", None)
+    if not webhook:
+        return
+    httpx.post(webhook, json={"text": message}).raise_for_status()
+
+
+def alert(certs: list[dict]):
+    for cert in certs:
+        if cert["days"] is None or cert["days"] > WARN_DAYS:
+            continue
+        level = "CRITICAL" if cert["days"] <= CRIT_DAYS else "WARNING"
+        usage = ", ".join(cert["usage"]) or "unused"
+        send_alert(
+            f"{level}: certificate {cert['name']} ({usage}) on {cert['device']} "
+            f"expires in {cert['days']} days ({cert['expires']})"
+        )
+
+
+def print_report(certs: list[dict]):
+    table = Table("Device", "Name", "Subject", "Issuer", "Usage", "Days", title="Certificates")
+    for cert in sorted(certs, key=lambda c: c["days"] if c["days"] is not None else sys.maxsize):
+        days = cert["days"]
+        style = None
+        if days is not None and days <= CRIT_DAYS:
+            style = "red"
+        elif days is not None and days <= WARN_DAYS:
+            style = "yellow"
+        table.add_row(
+            cert["device"],
+            cert["name"],
+            cert["subject"],
+            cert["issuer"],
+            ", ".join(cert["usage"]),
+            str(days),
+            style=style,
+        )
+    print(table)
+
+
+if __name__ == "__main__":
+    pano = Panorama(
+        hostname=config.HOST, api_username=config.USERNAME, api_password=config.PASSWORD
+    )
+    certs = certificates(running_config(pano), config.HOST)
+    for serial, hostname in pano.connected_firewalls().items():
+        certs += certificates(running_config(pano, serial), hostname or serial)
+    print_report(certs)
+    alert(certs)
@@ -0,0 +1,371 @@
+"""Security policy as code for Panorama device groups.
+
+Address objects, service objects and pre-rulebase security rules for the
+selected device groups are declared in YAML:
+
+    device_groups:
+      DC-Firewalls:
+        address_objects:
+          - {name: web-1, value: 10.1.1.10/32}
+        service_objects:
+          - {name: tcp-8443, protocol: tcp, destination_port: "8443"}
+        rules:
+          - name: allow-web
+            fromzone: [trust]
+            tozone: [untrust]
+            source: [web-1]
+            destination: [any]
+            application: [ssl]
+            action: allow
+        test_flows:
+          - {source: 10.1.1.10, destination: 8.8.8.8, protocol: 6, port: 443,
+             serial: "016401016351", expect: allow-web}
+
+The tool only manages what it owns: objects and rules tagged
+config.POLICY_TAG (default "policy-as-code"). Declared ones are tagged when
+they're created or changed, tagged ones that are no longer declared are
+deleted, and rules are kept in the declared order. Untagged objects, e.g.
+ones only used by NAT rules or address groups, are never touched.
+
+Usage:
+    python policy.py plan policy.yaml
+    python policy.py apply policy.yaml [--verify]
+    python policy.py verify policy.yaml
+
+apply changes the candidate config only; commit and push as usual. Test flows
+are evaluated against the firewall's running policy, so verify after a push,
+or use apply --verify, which commits, pushes the device groups and then runs
+the test flows.
+"""
+
+import argparse
+import sys
+from typing import Literal
+
+import config
+import yaml
+from panos.objects import AddressObject, ServiceObject, Tag
+from panos.panorama import DeviceGroup
+from panos.policies import PreRulebase, SecurityRule
+from pydantic import BaseModel
+from rich import print
+from subclass import Panorama
+
+MANAGED_TAG = getattr(config, "POLICY_TAG", "policy-as-code")
+
+
+class AddressSpec(BaseModel):
+    name: str
+    value: str
+    type: Literal["ip-netmask", "ip-range", "ip-wildcard", "fqdn"] = "ip-netmask"
+    description: str | None = None
+    tag: list[str] | None = None
+
+
+class ServiceSpec(BaseModel):
+    name: str
+    protocol: Literal["tcp", "udp", "sctp"]
+    destination_port: str
+    source_port: str | None = None
+    description: str | None = None
+    tag: list[str] | None = None
+
+
+class RuleSpec(BaseModel):
+    name: str
+    fromzone: list[str] = ["any"]
+    tozone: list[str] = ["any"]
+    source: list[str] = ["any"]
+    destination: list[str] = ["any"]
+    source_user: list[str] = ["any"]
+    application: list[str] = ["any"]
+    service: list[str] = ["application-default"]
+    category: list[str] = ["any"]
+    action: Literal["allow", "deny", "drop", "reset-client", "reset-server", "reset-both"]
+    description: str | None = None
+    tag: list[str] | None = None
+    log_end: bool = True
+
+
+class TestFlow(BaseModel):
+    source: str
+    destination: str
+    protocol: int
+    port: int
+    serial: str
+    expect: str
+
+
+class DeviceGroupPolicy(BaseModel):
+    address_objects: list[AddressSpec] = []
+    service_objects: list[ServiceSpec] = []
+    rules: list[RuleSpec] = []
+    test_flows: list[TestFlow] = []
+
+
+class PolicyFile(BaseModel):
+    device_groups: dict[str, DeviceGroupPolicy]
+
+
+# Declared model, pan-os-python class and where its live objects are attached
+KINDS = [
+    ("address_objects", AddressObject, "device_group"),
+    ("service_objects", ServiceObject, "device_group"),
+    ("rules", SecurityRule, "rulebase"),
+]
+
+
+def load_policy(path: str) -> PolicyFile:
+    with open(path) as f:
+        return PolicyFile(**yaml.safe_load(f))
+
+
+def _normalize(value):
+    if isinstance(value, str):
+        return [value]
+    return value
+
+
+def owned_tags(tags: list[str] | None) -> list[str]:
+    """The declared tags plus MANAGED_TAG, which marks the object as owned."""
+    tags = list(tags or [])
+    return tags if MANAGED_TAG in tags else tags + [MANAGED_TAG]
+
+
+def is_owned(live) -> bool:
+    return MANAGED_TAG in (_normalize(live.tag) or [])
+
+
+def changed_fields(spec: BaseModel, live) -> dict:
+    """Fields where the declared value differs from the live object, as (live, declared).
+
+    Only fields set in the YAML are compared, so defaults don't show up as
+    changes, plus the tags, so an existing object is tagged once it's declared.
+    """
+    current = live.about()
+    declared = spec.model_dump(exclude={"name"}, exclude_unset=True)
+    declared["tag"] = owned_tags(spec.tag)
+    return {
+        field: (current.get(field), value)
+        for field, value in declared.items()
+        if _normalize(current.get(field)) != _normalize(value)
+    }
+
+
+def plan_moves(live_names: list[str], declared_names: list[str]) -> list[tuple[str, str | None]]:
+    """Moves that put the live rules in their declared order.
+
+    Only declared rules that already exist are moved, each after its
+    predecessor (None for the top). New rules are placed when they're added,
+    and undeclared ones are deleted or left alone, so neither needs moving.
+
+    Returns:
+        (rule, predecessor) pairs, in the order they must be applied.
+    """
+    declared = [name for name in declared_names if name in live_names]
+    current = [name for name in live_names if name in declared]
+    moves = []
+    for index, name in enumerate(declared):
+        if current[index] != name:
+            predecessor = declared[index - 1] if index else None
+            moves.append((name, predecessor))
+            current.remove(name)
+            current.insert(index, name)
+    return moves
+
+
+def plan_device_group(pano: Panorama, name: str, policy: DeviceGroupPolicy) -> list[dict]:
+    """Diffs a device group's declared policy against the live config.
+
+    Returns:
+        Changes in the order they must be applied: the ownership tag is
+        added if it's missing, objects are added or changed before the rules
+        that use them, existing rules are moved into order before new rules
+        are placed after their predecessors, and rules are deleted before the
+        objects they used.
+    """
+    device_group = DeviceGroup(name)
+    pano.add(device_group)
+    rulebase = device_group.add(PreRulebase())
+    parents = {"device_group": device_group, "rulebase": rulebase}
+
+    upserts, deletes = [], []
+    tags = Tag.refreshall(pano, add=False) + Tag.refreshall(device_group, add=False)
+    if MANAGED_TAG not in {tag.name for tag in tags}:
+        upserts.append(
+            {
+                "device_group": name,
+                "kind": "tags",
+                "parent": device_group,
+                "action": "add",
+                "name": MANAGED_TAG,
+            }
+        )
+    for kind, cls, parent in KINDS:
+        live = {obj.name: obj for obj in cls.refreshall(parents[parent], add=True)}
+        declared = getattr(policy, kind)
+        common = {"device_group": name, "kind": kind, "parent": parents[parent]}
+        if kind == "rules":
+            for rule, predecessor in plan_moves(list(live), [spec.name for spec in declared]):
+                upserts.append(
+                    {
+                        **common,
+                        "action": "move",
+                        "name": rule,
+                        "object": live[rule],
+                        "after": predecessor,
+                    }
+                )
+        for spec in declared:
+            if spec.name not in live:
+                upserts.append({**common, "action": "add", "name": spec.name, "spec": spec})
+            elif diff := changed_fields(spec, live[spec.name]):
+                upserts.append(
+                    {
+                        **common,
+                        "action": "change",
+                        "name": spec.name,
+                        "spec": spec,
+                        "object": live[spec.name],
+                        "diff": diff,
+                    }
+                )
+        declared_names = {spec.name for spec in declared}
+        for obj_name, obj in live.items():
+            if obj_name not in declared_names and is_owned(obj):
+                deletes.append({**common, "action": "delete", "name": obj_name, "object": obj})
+
+    return upserts + list(reversed(deletes))
+
+
+def print_plan(changes: list[dict]):
+    if not changes:
+        print("[green]No changes[/green]")
+    colors = {"add": "green", "change": "yellow", "move": "cyan", "delete": "red"}
+    symbols = {"add": "+", "change": "~", "move": ">", "delete": "-"}
+    for change in changes:
+        color = colors[change["action"]]
+        print(
+            f"[{color}]{symbols[change['action']]} {change['device_group']} "
+            f"{change['kind']} {change['name']}[/{color}]"
+        )
+        for field, (old, new) in change.get("diff", {}).items():
+            print(f"    {field}: {old} -> {new}")
+        if change["action"] == "move":
+            print(f"    after {change['after']}" if change["after"] else "    to top")
+
+
+def apply_changes(changes: list[dict], policy: PolicyFile):
+    """Applies planned changes to the candidate config."""
+    classes = {kind: cls for kind, cls, _ in KINDS}
+    for change in changes:
+        print(f"{change['action']} {change['device_group']} {change['kind']} {change['name']}")
+        if change["action"] == "delete":
+            change["object"].delete()
+            continue
+        if change["kind"] == "tags":
+            change["parent"].add(Tag(change["name"])).create()
+            continue
+        if change["action"] == "move":
+            if change["after"]:
+                change["object"].move("after", change["after"])
+            else:
+                change["object"].move("top")
+            continue
+        spec = change["spec"]
+        if change["action"] == "change":
+            for field, (_, value) in change["diff"].items():
+                setattr(change["object"], field, value)
+            change["object"].apply()
+            continue
+        obj = classes[change["kind"]](**{**spec.model_dump(), "tag": owned_tags(spec.tag)})
+        change["parent"].add(obj)
+        obj.create()
+        if change["kind"] == "rules":
+            # New rules are created at the bottom; move them after the declared predecessor
+            rules = policy.device_groups[change["device_group"]].rules
+            index = [rule.name for rule in rules].index(spec.name)
+            if index == 0:
+                obj.move("top")
+            else:
+                obj.move("after", rules[index - 1].name)
+
+
+def commit_and_push(pano: Panorama, device_groups: list[str]) -> bool:
+    """Commits the candidate config to Panorama and pushes it to the device groups.
+
+    A commit with nothing to do returns no job result, and counts as a success.
+    """
+    commits = [("Panorama", lambda: pano.commit(sync=True))] + [
+        (name, lambda name=name: pano.commit_all(sync=True, devicegroup=name))
+        for name in device_groups
+    ]
+    for target, commit in commits:
+        print(f"commit {target}")
+        result = commit()
+        if result and not result["success"]:
+            print(f"[red]Commit to {target} failed: {result.get('messages')}[/red]")
+            return False
+    return True
+
+
+def matched_rule(result) -> str | None:
+    entry = result.find(".//rules/entry")
+    if entry is None:
+        return None
+    return entry.get("name") or (entry.text or "").strip()
+
+
+def verify(pano: Panorama, policy: PolicyFile) -> bool:
+    """Runs declared test flows and checks each matches the expected rule."""
+    ok = True
+    for name, device_group in policy.device_groups.items():
+        for flow in device_group.test_flows:
+            result = pano.test_security_policy_match(
+                source=flow.source,
+                destination=flow.destination,
+                protocol=flow.protocol,
+                port=flow.port,
+                serial=flow.serial,
+            )
+            rule = matched_rule(result)
+            passed = rule == flow.expect
+            ok = ok and passed
+            status = "[green]PASS[/green]" if passed else "[red]FAIL[/red]"
+            print(
+                f"{status} {name} {flow.source} -> {flow.destination} "
+                f"{flow.protocol}/{flow.port}: matched {rule}, expected {flow.expect}"
+            )
+    return ok
+
+
+if __name__ == "__main__":
+    parser = argparse.ArgumentParser(description=__doc__.splitlines()[0])
+    parser.add_argument("command", choices=["plan", "apply", "verify"])
+    parser.add_argument("policy_file")
+    parser.add_argument(
+        "--verify",
+        action="store_true",
+        help="After apply, commit, push the device groups and run the test flows",
+    )
+    args = parser.parse_args()
+
+    policy = load_policy(args.policy_file)
+    pano = Panorama(
+        hostname=config.HOST, api_username=config.USERNAME, api_password=config.PASSWORD
+    )
+    if args.command == "verify":
+        sys.exit(0 if verify(pano, policy) else 1)
+
+    changes = []
+    for name, device_group in policy.device_groups.items():
+        changes += plan_device_group(pano, name, device_group)
+    print_plan(changes)
+    if args.command == "apply" and changes:
+        apply_changes(changes, policy)
+    if args.command == "apply" and args.verify:
+        # Test flows are evaluated against the running policy, so push first
+        if changes and not commit_and_push(pano, list(policy.device_groups)):
+            sys.exit(1)
+        if not verify(pano, policy):
+            sys.exit(1)
@@ -2,9 +2,23 @@ from panos.panorama import Panorama as OriginalPanorama
 import config
 import ipdb
 import xml.etree.ElementTree as ET
+from opentelemetry import trace
+
+SERIAL = "016401016351"
+
+tracer = trace.get_tracer(__name__)
 
 
 class Panorama(OriginalPanorama):
+    def op(self, cmd=None, *args, **kwargs):
+        """Same as Panorama.op(), in a span naming the command and target firewall."""
+        command = cmd.decode() if isinstance(cmd, bytes) else str(cmd)
+        attributes = {"cmd": command}
+        if target := (kwargs.get("extra_qs") or {}).get("target"):
+            attributes["target"] = target
+        with tracer.start_as_current_span("panorama.op", attributes=attributes):
+            return super().op(cmd, *args, **kwargs)
+
     def test_security_policy_match(
         self,
         source: str,
@@ -17,26 +31,187 @@ class Panorama(OriginalPanorama):
         from_zone=None,
         to_zone=None,
         show_all=False,
+        serial=SERIAL,
     ):
         root = ET.Element("request-batch")
         op = ET.SubElement(root, "op-command")
         device = ET.SubElement(op, "device")
-        ET.SubElement(device, "entry", {"name": "016401016351"})
+        ET.SubElement(device, "entry", {"name": serial})
         test = ET.SubElement(op, "test")
         policy_match = ET.SubElement(test, "security-policy-match")
         ET.SubElement(policy_match, "source").text = source
         ET.SubElement(policy_match, "destination").text = destination
         ET.SubElement(policy_match, "destination-port").text = str(port)
         ET.SubElement(policy_match, "protocol").text = str(protocol)
+        if user:
+            ET.SubElement(policy_match, "source-user").text = user
 
         return self.op(cmd=ET.tostring(root), cmd_xml=False)
 
+    def connected_firewalls(self) -> dict[str, str]:
+        """Serial number to hostname of the firewalls connected to Panorama."""
+        result = self.op(cmd="<show><devices><connected/></devices></show>", cmd_xml=False)
+        return {
+            entry.findtext("serial"): entry.findtext("hostname")
+            for entry in result.findall(".//devices/entry")
+        }
+
+    def has_session(self, source: str, destination: str, serial: str) -> bool:
+        """Whether a managed firewall has a session from source to destination."""
+        root = ET.Element("show")
+        session = ET.SubElement(ET.SubElement(root, "session"), "all")
+        session_filter = ET.SubElement(session, "filter")
+        ET.SubElement(session_filter, "source").text = source
+        ET.SubElement(session_filter, "destination").text = destination
+        result = self.op(cmd=ET.tostring(root), cmd_xml=False, extra_qs={"target": serial})
+        return result.find(".//entry") is not None
+
+    def find_flow_firewall(self, source: str, destination: str | None = None) -> str:
+        """Finds the managed firewall that handles a flow.
+
+        That's the firewall with a session for the flow, if there is one,
+        otherwise the firewall with a User-ID mapping for the source.
+
+        Raises:
+            LookupError: No connected firewall has seen the flow or the source.
+        """
+        serials = self.connected_firewalls()
+        if destination:
+            for serial in serials:
+                if self.has_session(source, destination, serial):
+                    return serial
+        for serial in serials:
+            if self.get_ip_user_mapping(source, serial=serial):
+                return serial
+        raise LookupError(f"No connected firewall has a session or User-ID mapping for {source}")
+
+    def get_ip_user_mapping(self, ip: str, serial: str) -> dict | None:
+        """Looks up the user mapped to an IP address on a managed firewall.
+
+        Returns:
+            Dict with user, vsys and type (mapping source), or None if the IP
+            has no mapping.
+        """
+        root = ET.Element("show")
+        mapping = ET.SubElement(ET.SubElement(root, "user"), "ip-user-mapping")
+        ET.SubElement(mapping, "ip").text = ip
+        result = self.op(cmd=ET.tostring(root), cmd_xml=False, extra_qs={"target": serial})
+        entry = result.find(".//entry")
+        if entry is None:
+            return None
+        return {
+            "user": entry.findtext("user"),
+            "vsys": entry.findtext("vsys"),
+            "type": entry.findtext("type"),
+        }
+
+    def get_user_groups(self, user: str, serial: str) -> list[str]:
+        """Lists the groups a user belongs to on a managed firewall."""
+        root = ET.Element("show")
+        match = ET.SubElement(ET.SubElement(ET.SubElement(root, "user"), "user-ids"), "match-user")
+        match.text = user
+        result = self.op(cmd=ET.tostring(root), cmd_xml=False, extra_qs={"target": serial})
+        return parse_user_groups(result.findtext(".//result") or "")
+
+    def identify_endpoint(self, ip: str, serial: str) -> dict:
+        """Annotates an IP address with its user and the user's groups, if mapped."""
+        mapping = self.get_ip_user_mapping(ip, serial=serial)
+        if not mapping:
+            return {"ip": ip, "user": None, "groups": []}
+        return {
+            "ip": ip,
+            "user": mapping["user"],
+            "groups": self.get_user_groups(mapping["user"], serial=serial),
+        }
+
+    def annotate_trace(
+        self, results: dict, source: str, destination: str, serial: str | None = None
+    ) -> dict:
+        """Adds the users behind a trace's endpoints, under an "endpoints" key.
+
+        Args:
+            results: Traceroute results, e.g. from NAPALM.
+            source: Address the trace was run from.
+            destination: Address the trace was run to.
+            serial: Firewall to look the users up on. Defaults to the one
+                that handles the flow, see find_flow_firewall().
+        """
+        serial = serial or self.find_flow_firewall(source, destination)
+        results["endpoints"] = {
+            "serial": serial,
+            "source": self.identify_endpoint(source, serial=serial),
+            "destination": self.identify_endpoint(destination, serial=serial),
+        }
+        return results
+
+    def test_security_policy_match_with_user(
+        self, source: str, destination: str, protocol: int, port=None, serial: str | None = None
+    ) -> dict:
+        """Runs a policy match, then re-runs it as the source's mapped user.
+
+        The match runs on the given firewall, or by default the one that
+        handles the flow, see find_flow_firewall().
+
+        Returns:
+            Dict with the firewall serial, the annotated source and
+            destination, the match without a user, and the match with the
+            source user (None if unmapped).
+        """
+        serial = serial or self.find_flow_firewall(source, destination)
+        source_endpoint = self.identify_endpoint(source, serial=serial)
+        destination_endpoint = self.identify_endpoint(destination, serial=serial)
+        match = self.test_security_policy_match(
+            source=source, destination=destination, protocol=protocol, port=port, serial=serial
+        )
+        user_match = None
+        if source_endpoint["user"]:
+            user_match = self.test_security_policy_match(
+                source=source,
+                destination=destination,
+                protocol=protocol,
+                port=port,
+                user=source_endpoint["user"],
+                serial=serial,
+            )
+        return {
+            "serial": serial,
+            "source": source_endpoint,
+            "destination": destination_endpoint,
+            "match": match,
+            "user_match": user_match,
+        }
+
+
+def parse_user_groups(output: str) -> list[str]:
+    """Parses group DNs from "show user user-ids match-user" output, e.g.:
+
+    User Name        Vsys   Groups
+    ---------------------------------------------------------
+    corp\\jdoe        vsys1  cn=domain users,cn=users,dc=corp
+                            cn=vpn,ou=groups,dc=corp
+    """
+    groups = []
+    lines = output.splitlines()
+    for i, line in enumerate(lines):
+        if line.startswith("---"):
+            break
+    else:
+        return groups
+    for line in lines[i + 1 :]:
+        if not line.strip() or line.startswith("Total"):
+            continue
+        if line[0].isspace():
+            groups.append(line.strip())
+        elif len(fields := line.split(maxsplit=2)) == 3:
+            groups.append(fields[2].strip())
+    return groups
+
 
 if __name__ == "__main__":
     pano = Panorama(
         hostname=config.HOST, api_username=config.USERNAME, api_password=config.PASSWORD
     )
-    result = pano.test_security_policy_match(
+    result = pano.test_security_policy_match_with_user(
         source="10.1.1.1", destination="8.8.8.8", protocol=6, port=80
     )
     ipdb.set_trace()
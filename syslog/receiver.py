@@ -0,0 +1,221 @@
+"""Syslog receiver that indexes device logs for correlation with path events.
+
+Listens on UDP and TCP (config.SYSLOG_PORT, default 5514), parses RFC 3164
+and RFC 5424 messages and appends them to config.SYSLOG_DIR/<device>.jsonl.
+Devices are named from the inventory (config.INVENTORY) by sender address.
+Senders that aren't in the inventory are filed under their address: the
+hostname in the message is unauthenticated, so it's stored but never used as
+a file name. Times without a UTC offset are taken as UTC.
+
+Usage:
+    python receiver.py serve
+    python receiver.py around --time 2024-05-01T10:15:00+00:00 --devices rtr1,rtr2
+    python receiver.py around --time 2024-05-01T10:15:00+00:00 --trace results.json
+"""
+
+import argparse
+import json
+import re
+import socketserver
+import threading
+from datetime import datetime, timedelta, timezone
+from pathlib import Path
+
+import config
+import yaml
+from rich import print
+
+PORT = getattr(config, "SYSLOG_PORT", 5514)
+SYSLOG_DIR = Path(getattr(config, "SYSLOG_DIR", "logs"))
+SEVERITIES = ["emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"]
+
+RFC5424_PATTERN = re.compile(
+    r"^<(?P<pri>\d{1,3})>1 (?P<timestamp>\S+) (?P<host>\S+) (?P<app>\S+) \S+ \S+ "
+    r"(?P<sd>-|(\[.*?\])+) ?(?P<message>.*)$",
+    re.DOTALL,
+)
+RFC3164_PATTERN = re.compile(
+    r"^<(?P<pri>\d{1,3})>(?P<timestamp>[A-Z][a-z]{2} [ \d]\d \d\d:\d\d:\d\d) "
+    r"(?P<host>\S+) (?:(?P<app>[^:\[\s]+)(?:\[\d+\])?: )?(?P<message>.*)$",
+    re.DOTALL,
+)
+PRI_PATTERN = re.compile(r"^<(?P<pri>\d{1,3})>(?P<message>.*)$", re.DOTALL)
+
+
+def load_devices() -> dict[str, str]:
+    """Maps inventory host addresses to device names."""
+    inventory_file = getattr(config, "INVENTORY", None)
+    if not inventory_file or not Path(inventory_file).exists():
+        return {}
+    with open(inventory_file) as f:
+        devices = (yaml.safe_load(f) or {}).get("devices") or {}
+    return {device["host"]: name for name, device in devices.items()}
+
+
+def parse_message(data: str, sender: str, devices: dict[str, str]) -> dict:
+    """Parses a syslog message.
+
+    Messages that are neither RFC 5424 nor RFC 3164 (e.g. IOS with sequence
+    numbers) keep their facility and severity, with the rest as the message.
+    """
+    data = data.strip()
+    entry = {
+        "timestamp": datetime.now(timezone.utc).isoformat(),
+        "sender": sender,
+        "host": None,
+        "app": None,
+        "device_timestamp": None,
+        "facility": None,
+        "severity": None,
+        "message": data,
+    }
+    match = RFC5424_PATTERN.match(data) or RFC3164_PATTERN.match(data) or PRI_PATTERN.match(data)
+    if match:
+        fields = match.groupdict()
+        pri = int(fields["pri"])
+        entry.update(
+            facility=pri // 8,
+            severity=SEVERITIES[pri % 8],
+            message=fields["message"].strip(),
+            host=None if fields.get("host") in (None, "-") else fields["host"],
+            app=None if fields.get("app") in (None, "-") else fields["app"],
+            device_timestamp=fields.get("timestamp"),
+        )
+    entry["device"] = devices.get(sender) or sender
+    return entry
+
+
+def log_path(device: str, directory: Path = SYSLOG_DIR) -> Path:
+    """The log file for a device, kept inside the directory whatever the name."""
+    name = re.sub(r"[^\w.:-]", "_", device)
+    path = (directory / f"{name}.jsonl").resolve()
+    if not path.is_relative_to(directory.resolve()):
+        raise ValueError(f"Invalid device name {device!r}")
+    return path
+
+
+class Store:
+    def __init__(self, directory: Path = SYSLOG_DIR):
+        directory.mkdir(parents=True, exist_ok=True)
+        self.directory = directory
+        self.lock = threading.Lock()
+        self.devices = load_devices()
+
+    def add(self, data: str, sender: str):
+        entry = parse_message(data, sender, self.devices)
+        with self.lock, open(log_path(entry["device"], self.directory), "a") as f:
+            f.write(json.dumps(entry) + "\n")
+        print(f"{entry['device']} {entry['severity']} {entry['message']}")
+
+
+class UDPHandler(socketserver.BaseRequestHandler):
+    def handle(self):
+        data, _ = self.request
+        self.server.store.add(data.decode(errors="replace"), self.client_address[0])
+
+
+class TCPHandler(socketserver.StreamRequestHandler):
+    def handle(self):
+        # RFC 6587 framing: octet counting ("<length> <message>") or newline delimited
+        while first := self.rfile.read(1):
+            if first.isdigit():
+                length = first
+                while (char := self.rfile.read(1)).isdigit():
+                    length += char
+                data = self.rfile.read(int(length))
+            else:
+                data = first + self.rfile.readline()
+            text = data.decode(errors="replace")
+            if text.strip():
+                self.server.store.add(text, self.client_address[0])
+
+
+class UDPServer(socketserver.ThreadingUDPServer):
+    allow_reuse_address = True
+
+
+class TCPServer(socketserver.ThreadingTCPServer):
+    allow_reuse_address = True
+    daemon_threads = True
+
+
+def serve(port: int = PORT):
+    store = Store()
+    udp = UDPServer(("0.0.0.0", port), UDPHandler)
+    tcp = TCPServer(("0.0.0.0", port), TCPHandler)
+    udp.store = tcp.store = store
+    threading.Thread(target=udp.serve_forever, daemon=True).start()
+    print(f"Listening for syslog on UDP and TCP port {port}")
+    tcp.serve_forever()
+
+
+def parse_time(value: str) -> datetime:
+    """Parses an ISO 8601 time, taking one without a UTC offset as UTC."""
+    when = datetime.fromisoformat(value)
+    if when.tzinfo is None:
+        return when.replace(tzinfo=timezone.utc)
+    return when
+
+
+def logs_around(devices: list[str], when: datetime, window: int = 300) -> list[dict]:
+    """Log lines from the given devices within window seconds of a point in time."""
+    start, end = when - timedelta(seconds=window), when + timedelta(seconds=window)
+    entries = []
+    for device in devices:
+        log_file = log_path(device)
+        if not log_file.exists():
+            continue
+        with open(log_file) as f:
+            for line in f:
+                entry = json.loads(line)
+                if start <= parse_time(entry["timestamp"]) <= end:
+                    entries.append(entry)
+    return sorted(entries, key=lambda e: e["timestamp"])
+
+
+def trace_devices(results: dict, devices: dict[str, str]) -> list[str]:
+    """Device names on the path of a NAPALM traceroute result.
+
+    Hops are named as in napalm/correlate.py: by the devices mapping (IP
+    address -> name) if the hop address is in it, otherwise by the resolved
+    host name up to the "--" interface separator. Hops without a PTR name
+    are named by address, as the logs of devices outside the inventory are.
+    """
+    names = []
+    for hop in results.get("success", {}).values():
+        for probe in hop["probes"].values():
+            address = probe["ip_address"]
+            if address == "*":
+                continue
+            host_name = probe.get("host_name") or address
+            name = devices.get(address) or host_name.split("--")[0]
+            if name not in names:
+                names.append(name)
+    return names
+
+
+if __name__ == "__main__":
+    parser = argparse.ArgumentParser(description=__doc__.splitlines()[0])
+    commands = parser.add_subparsers(dest="command", required=True)
+    commands.add_parser("serve")
+    around = commands.add_parser("around", help="Show logs around a trace failure or path change")
+    around.add_argument("--time", required=True, type=parse_time)
+    around.add_argument("--window", type=int, default=300, help="Seconds either side")
+    path = around.add_mutually_exclusive_group(required=True)
+    path.add_argument("--devices", help="Comma-separated device names")
+    path.add_argument("--trace", help="JSON file with NAPALM traceroute results")
+    args = parser.parse_args()
+
+    if args.command == "serve":
+        serve()
+    else:
+        if args.trace:
+            with open(args.trace) as f:
+                devices = trace_devices(json.load(f), load_devices())
+        else:
+            devices = args.devices.split(",")
+        for entry in logs_around(devices, args.time, args.window):
+            print(
+                f"{entry['timestamp']} [bold]{entry['device']}[/bold] "
+                f"{entry['severity']} {entry['message']}"
+            )